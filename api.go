@@ -45,32 +45,15 @@ type AsyncResultParams struct {
 }
 
 // AsyncResult extracts gets an async result by contacting the API.
+// The result is empty while it is not available.
+// The context passed with WithContext cancels the poll.
 func (c *Client) AsyncResult(params AsyncResultParams, opts ...Option) (*AsyncResult, error) {
 	asyncResult := &AsyncResult{}
-
-	resp, err := http.Get(params.URL)
-	if err != nil {
-		return nil, err
-	}
-
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	opts = append(opts, urlOpt{url: params.URL})
+	err := c.issueRequest(http.MethodGet, "async-result", nil, asyncResult, opts...)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, &HTTPError{
-			Detail: string(body),
-			Status: resp.StatusCode,
-		}
-	}
-
-	if err = json.Unmarshal(body, asyncResult); err != nil {
-		return nil, err
-	}
-
 	return asyncResult, nil
 }
 
@@ -98,7 +81,7 @@ func (c *Client) Chatbot(params ChatbotParams, opts ...Option) (*Chatbot, error)
 
 // StreamingChatbot responds as a human by contacting the API, and returns a stream.
 func (c *Client) StreamingChatbot(params ChatbotParams, opts ...Option) (io.ReadCloser, error) {
-	streamBody, err := c.issueStreamingRequest(http.MethodPost, "chatbot", params)
+	streamBody, err := c.issueStreamingRequest(http.MethodPost, "chatbot", params, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -231,7 +214,7 @@ func (c *Client) Generation(params GenerationParams, opts ...Option) (*Generatio
 
 // StreamingGeneration generates a block of text by contacting the API, and returns a stream.
 func (c *Client) StreamingGeneration(params GenerationParams, opts ...Option) (io.ReadCloser, error) {
-	streamBody, err := c.issueStreamingRequest(http.MethodPost, "generation", params)
+	streamBody, err := c.issueStreamingRequest(http.MethodPost, "generation", params, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -245,9 +228,9 @@ type BatchGenerationParams struct {
 }
 
 // BatchGeneration generates a batch of blocks of text by contacting the API.
-func (c *Client) BatchGeneration(params BatchGenerationParams) (*BatchGeneration, error) {
+func (c *Client) BatchGeneration(params BatchGenerationParams, opts ...Option) (*BatchGeneration, error) {
 	batchGeneration := &BatchGeneration{}
-	err := c.issueRequest(http.MethodPost, "batch-generation", params, batchGeneration)
+	err := c.issueRequest(http.MethodPost, "batch-generation", params, batchGeneration, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -304,7 +287,7 @@ type KwKpExtractionParams struct {
 	Text string `json:"text"`
 }
 
-// AdGeneration generates a product description or an ad by contacting the API.
+// KwKpExtraction extracts keywords and keyphrases from a block of text by contacting the API.
 func (c *Client) KwKpExtraction(params KwKpExtractionParams, opts ...Option) (*KwKpExtraction, error) {
 	kwKpExtraction := &KwKpExtraction{}
 	err := c.issueRequest(http.MethodPost, "kw-kp-extraction", params, kwKpExtraction, opts...)
@@ -364,13 +347,13 @@ func (c *Client) Question(params QuestionParams, opts ...Option) (*Question, err
 // SemanticSearchParams wraps all the parameters for the "semantic-search" endpoint.
 type SemanticSearchParams struct {
 	Text       string `json:"text"`
-	NumResults int    `json:"num_results"`
+	NumResults int    `json:"num_results,omitempty"`
 }
 
-// SemanticSearch performs semantic search on custom data contacting the API.
+// SemanticSearch performs semantic search on custom data by contacting the API.
 func (c *Client) SemanticSearch(params SemanticSearchParams, opts ...Option) (*SemanticSearch, error) {
 	semanticSearch := &SemanticSearch{}
-	err := c.issueRequest(http.MethodPost, "semantic-similarity", params, semanticSearch, opts...)
+	err := c.issueRequest(http.MethodPost, "semantic-search", params, semanticSearch, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -457,7 +440,7 @@ func (c *Client) Summarization(params SummarizationParams, opts ...Option) (*Sum
 // BatchSummarizationParams wraps all the parameters for the "batch-summarization" endpoint.
 type BatchSummarizationParams struct {
 	Texts []string `json:"texts"`
-	Size  string   `json:"size"`
+	Size  string   `json:"size,omitempty"`
 }
 
 // BatchSummarization summarizes a batch of blocks of text by contacting the API.
@@ -578,7 +561,8 @@ type Classification struct {
 	Scores []float64 `json:"scores"`
 }
 
-// BatchClassification holds a batch of scores returned by the API.
+// BatchClassification holds a batch of scores returned by the API, one
+// score per text for the labels of the request.
 type BatchClassification struct {
 	Scores []float64 `json:"scores"`
 }
@@ -688,6 +672,34 @@ type ScoredLabel struct {
 type SearchResult struct {
 	Score float64 `json:"score"`
 	Text  string  `json:"text"`
+	// ID is the identifier of the matching document in the dataset, when
+	// returned by the API.
+	ID string `json:"id,omitempty"`
+	// Metadata holds the additional columns of the matching document in
+	// the dataset, when returned by the API.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// UnmarshalJSON unmarshals a search result, accepting both numeric and
+// string document identifiers.
+func (s *SearchResult) UnmarshalJSON(data []byte) error {
+	type searchResult SearchResult
+	var raw struct {
+		searchResult
+		ID json.RawMessage `json:"id,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = SearchResult(raw.searchResult)
+	if len(raw.ID) > 0 && string(raw.ID) != "null" {
+		var id string
+		if err := json.Unmarshal(raw.ID, &id); err != nil {
+			id = string(raw.ID)
+		}
+		s.ID = id
+	}
+	return nil
 }
 
 // SemanticSearch holds semantic search results returned by the API.
@@ -0,0 +1,174 @@
+package nlpcloud
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// endpointDoc matches the endpoint named by the doc comment of a params
+// type.
+var endpointDoc = regexp.MustCompile(`the "([a-z-]+)" endpoint`)
+
+// paramsEndpoints returns the endpoints named by the doc comments of the
+// params types of api.go, by type name.
+func paramsEndpoints(t *testing.T) map[string]string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "api.go", nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endpoints := map[string]string{}
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			if match := endpointDoc.FindStringSubmatch(gen.Doc.Text()); match != nil {
+				endpoints[spec.(*ast.TypeSpec).Name.Name] = match[1]
+			}
+		}
+	}
+	return endpoints
+}
+
+func TestEndpoints(t *testing.T) {
+	endpoints := paramsEndpoints(t)
+	for _, method := range parseClientMethods(t, "api.go", func(string) bool { return true }) {
+		params, ok := method.Decl.Type.Params.List[0].Type.(*ast.Ident)
+		if !ok {
+			t.Errorf("%s: unexpected params type", method.Name)
+			continue
+		}
+		endpoint, ok := endpoints[params.Name]
+		if !ok {
+			t.Errorf("%s: the doc comment of %s names no endpoint", method.Name, params.Name)
+			continue
+		}
+		call, ok := clientCalls[method.Name]
+		if !ok {
+			t.Errorf("%s: missing from clientCalls", method.Name)
+			continue
+		}
+
+		var got *http.Request
+		client := NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+			got = req
+			return stubResponse(http.StatusOK, "[DONE]"), nil
+		}), ClientParams{Model: "model", Token: "token"})
+		call(client)
+		if got == nil {
+			t.Errorf("%s: no request sent", method.Name)
+			continue
+		}
+
+		wantMethod, wantPath := http.MethodPost, "/v1/model/"+endpoint
+		if endpoint == "async-result" {
+			// The result is fetched from the URL returned by the API
+			wantMethod, wantPath = http.MethodGet, "/v1/get-async-result/1"
+		}
+		if got.Method != wantMethod || got.URL.Path != wantPath {
+			t.Errorf("%s: got %s %s, want %s %s", method.Name, got.Method, got.URL.Path, wantMethod, wantPath)
+		}
+	}
+}
+
+// newServerClient returns a Client sending all the requests to a test
+// server serving handler.
+func newServerClient(t *testing.T, handler http.HandlerFunc, params ClientParams) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, _ := url.Parse(server.URL)
+	transport := server.Client().Transport
+	return NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return transport.RoundTrip(req)
+	}), params)
+}
+
+func TestSemanticSearch(t *testing.T) {
+	var bodies []map[string]interface{}
+	client := newServerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/gpu/custom-model-1/semantic-search" {
+			http.Error(w, `{"detail":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, `{"detail":"Invalid JSON body."}`, http.StatusBadRequest)
+			return
+		}
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"search_results":[
+			{"score":0.9,"text":"Housing prices are rising.","id":12,"metadata":{"city":"Paris"}},
+			{"score":0.5,"text":"Rents are stable.","id":"doc-3"}
+		]}`))
+	}, ClientParams{Model: "custom-model-1", Token: "token", GPU: true})
+
+	search, err := client.SemanticSearch(SemanticSearchParams{Text: "How are housing prices?", NumResults: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SearchResult{
+		{Score: 0.9, Text: "Housing prices are rising.", ID: "12", Metadata: map[string]interface{}{"city": "Paris"}},
+		{Score: 0.5, Text: "Rents are stable.", ID: "doc-3"},
+	}
+	if !reflect.DeepEqual(search.SearchResults, want) {
+		t.Errorf("got results %+v, want %+v", search.SearchResults, want)
+	}
+
+	if _, err = client.SemanticSearch(SemanticSearchParams{Text: "How are housing prices?"}); err != nil {
+		t.Fatal(err)
+	}
+	wantBodies := []map[string]interface{}{
+		{"text": "How are housing prices?", "num_results": float64(2)},
+		{"text": "How are housing prices?"},
+	}
+	if !reflect.DeepEqual(bodies, wantBodies) {
+		t.Errorf("got bodies %v, want %v", bodies, wantBodies)
+	}
+}
+
+func TestSearchResultUnmarshal(t *testing.T) {
+	tests := []struct {
+		data string
+		want SearchResult
+	}{
+		{data: `{"score":0.5,"text":"text","id":12}`, want: SearchResult{Score: 0.5, Text: "text", ID: "12"}},
+		{data: `{"score":0.5,"text":"text","id":1.5}`, want: SearchResult{Score: 0.5, Text: "text", ID: "1.5"}},
+		{data: `{"score":0.5,"text":"text","id":"doc-1"}`, want: SearchResult{Score: 0.5, Text: "text", ID: "doc-1"}},
+		{data: `{"score":0.5,"text":"text","id":null}`, want: SearchResult{Score: 0.5, Text: "text"}},
+		{data: `{"score":0.5,"text":"text"}`, want: SearchResult{Score: 0.5, Text: "text"}},
+		{
+			data: `{"score":0.5,"text":"text","id":"doc-1","metadata":{"year":2020,"tags":["a","b"]}}`,
+			want: SearchResult{Score: 0.5, Text: "text", ID: "doc-1", Metadata: map[string]interface{}{
+				"year": float64(2020),
+				"tags": []interface{}{"a", "b"},
+			}},
+		},
+	}
+	for _, test := range tests {
+		var got SearchResult
+		if err := json.Unmarshal([]byte(test.data), &got); err != nil {
+			t.Errorf("%s: %v", test.data, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.data, got, test.want)
+		}
+	}
+
+	var got SearchResult
+	if err := json.Unmarshal([]byte(`{"score":"high"}`), &got); err == nil {
+		t.Errorf("invalid score: got no error")
+	}
+}
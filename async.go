@@ -0,0 +1,243 @@
+package nlpcloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrAsyncPending is returned when decoding an async result that is not
+// available yet.
+var ErrAsyncPending = errors.New("async result is not available yet")
+
+// AsyncAcceptedError is returned by the endpoints of an asynchronous Client
+// (see ClientParams.Async), as the API only returns the URL of the result.
+// The result can be waited for with ResumeAsyncJob.
+type AsyncAcceptedError struct {
+	URL string
+}
+
+func (e AsyncAcceptedError) Error() string {
+	return fmt.Sprintf("request accepted for asynchronous processing, result at %v", e.URL)
+}
+
+var _ error = (*AsyncAcceptedError)(nil)
+
+// PollPolicy defines how often the result of an AsyncJob is polled.
+type PollPolicy struct {
+	// Interval is the delay before the first poll.
+	Interval time.Duration
+	// Multiplier increases the delay after each poll. A value lower than
+	// 1 keeps the delay constant.
+	Multiplier float64
+	// MaxInterval caps the delay between two polls. Zero means no cap.
+	MaxInterval time.Duration
+}
+
+// DefaultPollPolicy returns a PollPolicy starting at 2 seconds and
+// slowing down up to 10 seconds between polls.
+func DefaultPollPolicy() PollPolicy {
+	return PollPolicy{
+		Interval:    2 * time.Second,
+		Multiplier:  1.5,
+		MaxInterval: 10 * time.Second,
+	}
+}
+
+func (p PollPolicy) next(delay time.Duration) time.Duration {
+	if p.Multiplier > 1 {
+		delay = time.Duration(float64(delay) * p.Multiplier)
+	}
+	if p.MaxInterval > 0 && delay > p.MaxInterval {
+		delay = p.MaxInterval
+	}
+	return delay
+}
+
+// AsyncJob is a handle on a request processed asynchronously by the API.
+type AsyncJob struct {
+	// URL is the URL of the result.
+	URL string
+	// Endpoint is the endpoint the request was sent to.
+	Endpoint string
+	// PollPolicy defines how often Wait polls the result.
+	PollPolicy PollPolicy
+
+	client *Client
+}
+
+// ResumeAsyncJob returns a handle on a request previously accepted for
+// asynchronous processing, e.g. from an AsyncAcceptedError.
+func (c *Client) ResumeAsyncJob(url, endpoint string) *AsyncJob {
+	return &AsyncJob{
+		URL:        url,
+		Endpoint:   endpoint,
+		PollPolicy: DefaultPollPolicy(),
+		client:     c,
+	}
+}
+
+// submitAsync sends a request to the asynchronous API whatever the
+// ClientParams.Async value, and returns the job handle.
+func (c *Client) submitAsync(endpoint string, params interface{}, opts []Option) (*AsyncJob, error) {
+	async := &Async{}
+	opts = append([]Option{rootURLOpt{rootURL: c.asyncRootURL}}, opts...)
+	err := c.issueRequest(http.MethodPost, endpoint, params, async, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.ResumeAsyncJob(async.URL, endpoint), nil
+}
+
+// Poll gets the result once. The result is not available yet if its
+// Done method returns false.
+func (j *AsyncJob) Poll(opts ...Option) (*AsyncResult, error) {
+	return j.client.AsyncResult(AsyncResultParams{URL: j.URL}, opts...)
+}
+
+// Wait polls the result according to the PollPolicy until it is
+// available, or until ctx is done.
+func (j *AsyncJob) Wait(ctx context.Context, opts ...Option) (*AsyncResult, error) {
+	opts = append([]Option{WithContext(ctx)}, opts...)
+	delay := j.PollPolicy.Interval
+	for {
+		if err := sleepContext(ctx, delay); err != nil {
+			return nil, err
+		}
+		result, err := j.Poll(opts...)
+		if err != nil {
+			return nil, err
+		}
+		if result.Done() {
+			return result, nil
+		}
+		delay = j.PollPolicy.next(delay)
+	}
+}
+
+// WaitFor waits for the result and decodes it into dst, which should be
+// the result type of the job endpoint (e.g. *Summarization).
+func (j *AsyncJob) WaitFor(ctx context.Context, dst interface{}, opts ...Option) error {
+	result, err := j.Wait(ctx, opts...)
+	if err != nil {
+		return err
+	}
+	return result.Decode(dst)
+}
+
+// Done reports whether the async result is available.
+func (r *AsyncResult) Done() bool {
+	return r.HTTPCode != 0
+}
+
+// Decode unmarshals the content of the async result into dst, which
+// should be the result type of the job endpoint (e.g. *Summarization).
+// A failed job is returned as an *HTTPError.
+func (r *AsyncResult) Decode(dst interface{}) error {
+	if !r.Done() {
+		return ErrAsyncPending
+	}
+	if r.HTTPCode != http.StatusOK || r.ErrorDetail != "" {
+		return &HTTPError{
+			Detail: r.ErrorDetail,
+			Status: r.HTTPCode,
+			Body:   r.Content,
+		}
+	}
+	return json.Unmarshal([]byte(r.Content), dst)
+}
+
+// ASRJob is an "asr" request processed asynchronously.
+type ASRJob struct {
+	*AsyncJob
+}
+
+// AsyncASR extracts text from an audio file by contacting the asynchronous API.
+func (c *Client) AsyncASR(params ASRParams, opts ...Option) (*ASRJob, error) {
+	job, err := c.submitAsync("asr", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &ASRJob{job}, nil
+}
+
+// Wait waits for the ASR result.
+func (j *ASRJob) Wait(ctx context.Context, opts ...Option) (*ASR, error) {
+	asr := &ASR{}
+	if err := j.WaitFor(ctx, asr, opts...); err != nil {
+		return nil, err
+	}
+	return asr, nil
+}
+
+// GenerationJob is a "generation" request processed asynchronously.
+type GenerationJob struct {
+	*AsyncJob
+}
+
+// AsyncGeneration generates a block of text by contacting the asynchronous API.
+func (c *Client) AsyncGeneration(params GenerationParams, opts ...Option) (*GenerationJob, error) {
+	job, err := c.submitAsync("generation", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &GenerationJob{job}, nil
+}
+
+// Wait waits for the Generation result.
+func (j *GenerationJob) Wait(ctx context.Context, opts ...Option) (*Generation, error) {
+	generation := &Generation{}
+	if err := j.WaitFor(ctx, generation, opts...); err != nil {
+		return nil, err
+	}
+	return generation, nil
+}
+
+// SummarizationJob is a "summarization" request processed asynchronously.
+type SummarizationJob struct {
+	*AsyncJob
+}
+
+// AsyncSummarization summarizes a block of text by contacting the asynchronous API.
+func (c *Client) AsyncSummarization(params SummarizationParams, opts ...Option) (*SummarizationJob, error) {
+	job, err := c.submitAsync("summarization", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &SummarizationJob{job}, nil
+}
+
+// Wait waits for the Summarization result.
+func (j *SummarizationJob) Wait(ctx context.Context, opts ...Option) (*Summarization, error) {
+	summarization := &Summarization{}
+	if err := j.WaitFor(ctx, summarization, opts...); err != nil {
+		return nil, err
+	}
+	return summarization, nil
+}
+
+// TranslationJob is a "translation" request processed asynchronously.
+type TranslationJob struct {
+	*AsyncJob
+}
+
+// AsyncTranslation translates a block of text by contacting the asynchronous API.
+func (c *Client) AsyncTranslation(params TranslationParams, opts ...Option) (*TranslationJob, error) {
+	job, err := c.submitAsync("translation", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &TranslationJob{job}, nil
+}
+
+// Wait waits for the Translation result.
+func (j *TranslationJob) Wait(ctx context.Context, opts ...Option) (*Translation, error) {
+	translation := &Translation{}
+	if err := j.WaitFor(ctx, translation, opts...); err != nil {
+		return nil, err
+	}
+	return translation, nil
+}
@@ -0,0 +1,360 @@
+package nlpcloud
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// BatcherParams wraps all the parameters for the Batcher initialization.
+type BatcherParams struct {
+	// MaxBatchSize is the maximum number of requests grouped in a batch.
+	// Default is 8.
+	MaxBatchSize int
+	// Window is how long a batch waits for more requests before being
+	// sent. Default is 20 milliseconds.
+	Window time.Duration
+}
+
+// Batcher groups the single requests issued concurrently into the
+// corresponding batch endpoints ("batch-summarization", "batch-translation",
+// "batch-generation" and "batch-classification"), and fans the results back
+// to the callers. When a batch fails, its requests are issued one by one,
+// unless it failed on authentication or rate limiting.
+//
+// Requests that cannot be expressed as a batch (e.g. a generation with a
+// max length) are issued directly. A Batcher is safe for concurrent use.
+type Batcher struct {
+	client  *Client
+	maxSize int
+	window  time.Duration
+
+	mu     sync.Mutex
+	queues map[string]*batchQueue
+}
+
+// Makes sure the *Batcher works with the batched capabilities.
+var (
+	_ Summarizer = (*Batcher)(nil)
+	_ Translator = (*Batcher)(nil)
+	_ Generator  = (*Batcher)(nil)
+	_ Classifier = (*Batcher)(nil)
+)
+
+// NewBatcher initializes a new Batcher issuing the requests with client.
+func NewBatcher(client *Client, params BatcherParams) *Batcher {
+	if params.MaxBatchSize < 1 {
+		params.MaxBatchSize = 8
+	}
+	if params.Window <= 0 {
+		params.Window = 20 * time.Millisecond
+	}
+	return &Batcher{
+		client:  client,
+		maxSize: params.MaxBatchSize,
+		window:  params.Window,
+		queues:  map[string]*batchQueue{},
+	}
+}
+
+// batchKind describes how single requests of an endpoint are batched.
+type batchKind struct {
+	endpoint string
+	// group returns the key of the batches the params can be grouped in,
+	// or false if the params cannot be batched.
+	group func(params interface{}) (string, bool)
+	// batch issues a batch request and returns one result per params.
+	batch func(c *Client, params []interface{}, opts []Option) ([]interface{}, error)
+	// single issues a single request.
+	single func(c *Client, params interface{}, opts []Option) (interface{}, error)
+}
+
+type batchItem struct {
+	params interface{}
+	opts   []Option
+	result chan batchResult
+}
+
+type batchResult struct {
+	value interface{}
+	err   error
+}
+
+type batchQueue struct {
+	kind  *batchKind
+	items []*batchItem
+	timer *time.Timer
+}
+
+// submit queues a request and waits for its result.
+func (b *Batcher) submit(kind *batchKind, params interface{}, opts []Option) (interface{}, error) {
+	group, ok := kind.group(params)
+	if !ok {
+		return kind.single(b.client, params, opts)
+	}
+
+	item := &batchItem{params: params, opts: opts, result: make(chan batchResult, 1)}
+	key := kind.endpoint + "\x00" + group
+
+	b.mu.Lock()
+	queue, ok := b.queues[key]
+	if !ok {
+		queue = &batchQueue{kind: kind}
+		b.queues[key] = queue
+		queue.timer = time.AfterFunc(b.window, func() {
+			b.mu.Lock()
+			if b.queues[key] != queue {
+				b.mu.Unlock()
+				return
+			}
+			delete(b.queues, key)
+			b.mu.Unlock()
+			b.flush(queue)
+		})
+	}
+	queue.items = append(queue.items, item)
+	if len(queue.items) >= b.maxSize {
+		delete(b.queues, key)
+		queue.timer.Stop()
+		go b.flush(queue)
+	}
+	b.mu.Unlock()
+
+	ctx := b.client.newOptions(opts).Ctx
+	select {
+	case result := <-item.result:
+		return result.value, result.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Flush sends the pending batches without waiting for their window.
+func (b *Batcher) Flush() {
+	b.mu.Lock()
+	queues := b.queues
+	b.queues = map[string]*batchQueue{}
+	b.mu.Unlock()
+
+	for _, queue := range queues {
+		queue.timer.Stop()
+		go b.flush(queue)
+	}
+}
+
+// flush issues a batch, falling back to single requests on failure.
+// The batch is issued with the options of its first request, but is not
+// cancelled by the callers' contexts.
+//
+// Authentication and rate limiting errors are returned to all the callers,
+// as the single requests would fail the same way.
+func (b *Batcher) flush(queue *batchQueue) {
+	first := queue.items[0]
+	ctx := detachedContext{b.client.newOptions(first.opts).Ctx}
+	opts := append(append([]Option(nil), first.opts...), WithContext(ctx))
+
+	params := make([]interface{}, len(queue.items))
+	for i, item := range queue.items {
+		params[i] = item.params
+	}
+	results, err := queue.kind.batch(b.client, params, opts)
+	if err == nil && len(results) != len(queue.items) {
+		err = fmt.Errorf("batch returned %d results for %d requests", len(results), len(queue.items))
+	}
+	if err == nil {
+		for i, item := range queue.items {
+			item.result <- batchResult{value: results[i]}
+		}
+		return
+	}
+	if errors.Is(err, ErrUnauthorized) || errors.Is(err, ErrForbidden) || errors.Is(err, ErrRateLimited) {
+		for _, item := range queue.items {
+			item.result <- batchResult{err: err}
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, item := range queue.items {
+		wg.Add(1)
+		go func(item *batchItem) {
+			defer wg.Done()
+			value, err := queue.kind.single(b.client, item.params, item.opts)
+			item.result <- batchResult{value: value, err: err}
+		}(item)
+	}
+	wg.Wait()
+}
+
+var summarizationBatch = &batchKind{
+	endpoint: "summarization",
+	group: func(params interface{}) (string, bool) {
+		size := params.(SummarizationParams).Size
+		if size == nil {
+			return "", true
+		}
+		return *size, true
+	},
+	batch: func(c *Client, params []interface{}, opts []Option) ([]interface{}, error) {
+		batchParams := BatchSummarizationParams{}
+		for _, p := range params {
+			p := p.(SummarizationParams)
+			batchParams.Texts = append(batchParams.Texts, p.Text)
+			if p.Size != nil {
+				batchParams.Size = *p.Size
+			}
+		}
+		batch, err := c.BatchSummarization(batchParams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, len(batch.SummaryTexts))
+		for i, text := range batch.SummaryTexts {
+			results[i] = &Summarization{SummaryText: text}
+		}
+		return results, nil
+	},
+	single: func(c *Client, params interface{}, opts []Option) (interface{}, error) {
+		return c.Summarization(params.(SummarizationParams), opts...)
+	},
+}
+
+// Summarization summarizes a block of text as part of a batch.
+func (b *Batcher) Summarization(params SummarizationParams, opts ...Option) (*Summarization, error) {
+	result, err := b.submit(summarizationBatch, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Summarization), nil
+}
+
+var translationBatch = &batchKind{
+	endpoint: "translation",
+	group: func(params interface{}) (string, bool) {
+		p := params.(TranslationParams)
+		return fmt.Sprintf("%t/%t", p.Source != nil, p.Target != nil), true
+	},
+	batch: func(c *Client, params []interface{}, opts []Option) ([]interface{}, error) {
+		batchParams := BatchTranslationParams{}
+		var sources, targets []string
+		for _, p := range params {
+			p := p.(TranslationParams)
+			batchParams.Texts = append(batchParams.Texts, p.Text)
+			if p.Source != nil {
+				sources = append(sources, *p.Source)
+			}
+			if p.Target != nil {
+				targets = append(targets, *p.Target)
+			}
+		}
+		if sources != nil {
+			batchParams.Sources = &sources
+		}
+		if targets != nil {
+			batchParams.Targets = &targets
+		}
+		batch, err := c.BatchTranslation(batchParams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, len(batch.TranslationTexts))
+		for i, text := range batch.TranslationTexts {
+			results[i] = &Translation{TranslationText: text}
+		}
+		return results, nil
+	},
+	single: func(c *Client, params interface{}, opts []Option) (interface{}, error) {
+		return c.Translation(params.(TranslationParams), opts...)
+	},
+}
+
+// Translation translates a block of text as part of a batch.
+func (b *Batcher) Translation(params TranslationParams, opts ...Option) (*Translation, error) {
+	result, err := b.submit(translationBatch, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Translation), nil
+}
+
+var generationBatch = &batchKind{
+	endpoint: "generation",
+	group: func(params interface{}) (string, bool) {
+		// The batch endpoint only accepts texts
+		p := params.(GenerationParams)
+		return "", reflect.DeepEqual(p, GenerationParams{Text: p.Text})
+	},
+	batch: func(c *Client, params []interface{}, opts []Option) ([]interface{}, error) {
+		batchParams := BatchGenerationParams{}
+		for _, p := range params {
+			batchParams.Texts = append(batchParams.Texts, p.(GenerationParams).Text)
+		}
+		batch, err := c.BatchGeneration(batchParams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, len(batch.Generations))
+		for i := range batch.Generations {
+			results[i] = &batch.Generations[i]
+		}
+		return results, nil
+	},
+	single: func(c *Client, params interface{}, opts []Option) (interface{}, error) {
+		return c.Generation(params.(GenerationParams), opts...)
+	},
+}
+
+// Generation generates a block of text as part of a batch.
+func (b *Batcher) Generation(params GenerationParams, opts ...Option) (*Generation, error) {
+	result, err := b.submit(generationBatch, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Generation), nil
+}
+
+var classificationBatch = &batchKind{
+	endpoint: "classification",
+	group: func(params interface{}) (string, bool) {
+		// The batch endpoint returns one score per text, so only the
+		// classifications with a single label and no multi class fit
+		p := params.(ClassificationParams)
+		if p.Labels == nil || len(*p.Labels) != 1 || p.MultiClass != nil {
+			return "", false
+		}
+		return (*p.Labels)[0], true
+	},
+	batch: func(c *Client, params []interface{}, opts []Option) ([]interface{}, error) {
+		label := (*params[0].(ClassificationParams).Labels)[0]
+		batchParams := BatchClassificationParams{Labels: []string{label}}
+		for _, p := range params {
+			batchParams.Texts = append(batchParams.Texts, p.(ClassificationParams).Text)
+		}
+		batch, err := c.BatchClassification(batchParams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if len(batch.Scores) != len(params) {
+			return nil, errUnexpectedCount(len(batch.Scores), len(params))
+		}
+		results := make([]interface{}, len(params))
+		for i := range results {
+			results[i] = &Classification{Labels: []string{label}, Scores: []float64{batch.Scores[i]}}
+		}
+		return results, nil
+	},
+	single: func(c *Client, params interface{}, opts []Option) (interface{}, error) {
+		return c.Classification(params.(ClassificationParams), opts...)
+	},
+}
+
+// Classification applies scored labels to a block of text as part of a batch.
+func (b *Batcher) Classification(params ClassificationParams, opts ...Option) (*Classification, error) {
+	result, err := b.submit(classificationBatch, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.(*Classification), nil
+}
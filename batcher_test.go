@@ -0,0 +1,113 @@
+package nlpcloud_test
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+// classifyConcurrently classifies n texts concurrently with the batcher,
+// and returns the results in order.
+func classifyConcurrently(batcher *nlpcloud.Batcher, n int, labels ...string) ([]*nlpcloud.Classification, []error) {
+	results := make([]*nlpcloud.Classification, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = batcher.Classification(nlpcloud.ClassificationParams{Text: "text", Labels: &labels})
+		}(i)
+	}
+	wg.Wait()
+	return results, errs
+}
+
+func TestBatcherClassification(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+	batcher := nlpcloud.NewBatcher(client, nlpcloud.BatcherParams{MaxBatchSize: 5, Window: time.Minute})
+
+	results, errs := classifyConcurrently(batcher, 5, "sport")
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("classification %d: %v", i, err)
+		}
+		if len(results[i].Labels) != 1 || results[i].Labels[0] != "sport" || len(results[i].Scores) != 1 {
+			t.Errorf("classification %d: got %+v", i, results[i])
+		}
+	}
+
+	requests := server.Requests()
+	if len(requests) != 1 || requests[0].Endpoint != "batch-classification" {
+		t.Fatalf("got %d requests, want 1 batch-classification request", len(requests))
+	}
+	var params nlpcloud.BatchClassificationParams
+	if err := requests[0].Decode(&params); err != nil {
+		t.Fatal(err)
+	}
+	if len(params.Texts) != 5 || len(params.Labels) != 1 {
+		t.Errorf("got batch %+v", params)
+	}
+}
+
+func TestBatcherDirectRequests(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+	batcher := nlpcloud.NewBatcher(client, nlpcloud.BatcherParams{})
+
+	// The batch endpoint returns one score per text, which cannot hold the
+	// scores of several labels
+	if _, errs := classifyConcurrently(batcher, 2, "sport", "politics"); errs[0] != nil || errs[1] != nil {
+		t.Fatal(errs)
+	}
+	if n := len(server.RequestsFor("classification")); n != 2 {
+		t.Errorf("got %d classification requests, want 2", n)
+	}
+	if n := len(server.RequestsFor("batch-classification")); n != 0 {
+		t.Errorf("got %d batch-classification requests, want 0", n)
+	}
+}
+
+func TestBatcherFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		response nlpcloudtest.Response
+		err      error
+		singles  int
+	}{
+		{name: "server error", response: nlpcloudtest.ServerError(http.StatusInternalServerError), singles: 3},
+		{name: "unauthorized", response: nlpcloudtest.Error(http.StatusUnauthorized, "Invalid token."), err: nlpcloud.ErrUnauthorized},
+		{name: "forbidden", response: nlpcloudtest.Error(http.StatusForbidden, "Forbidden."), err: nlpcloud.ErrForbidden},
+		{name: "rate limited", response: nlpcloudtest.RateLimited(time.Second), err: nlpcloud.ErrRateLimited},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := nlpcloudtest.NewServer("token")
+			defer server.Close()
+			client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+			batcher := nlpcloud.NewBatcher(client, nlpcloud.BatcherParams{MaxBatchSize: 3, Window: time.Minute})
+
+			server.Enqueue("batch-classification", test.response)
+			_, errs := classifyConcurrently(batcher, 3, "sport")
+			for i, err := range errs {
+				if test.err == nil && err != nil || test.err != nil && !errors.Is(err, test.err) {
+					t.Errorf("classification %d: got error %v, want %v", i, err, test.err)
+				}
+			}
+			if n := len(server.RequestsFor("batch-classification")); n != 1 {
+				t.Errorf("got %d batch-classification requests, want 1", n)
+			}
+			if n := len(server.RequestsFor("classification")); n != test.singles {
+				t.Errorf("got %d classification requests, want %d", n, test.singles)
+			}
+		})
+	}
+}
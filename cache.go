@@ -0,0 +1,229 @@
+package nlpcloud
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache defines what a cache have to implement in order to store the
+// responses of the Client. It must be safe for concurrent use.
+type Cache interface {
+	// Get returns the value stored for key, if any.
+	Get(key string) ([]byte, bool)
+	// Set stores value for key.
+	Set(key string, value []byte)
+}
+
+// CacheStats holds the statistics of the client cache.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Bypasses counts the requests that cannot be cached, like
+	// non-deterministic generations.
+	Bypasses uint64
+}
+
+// cacheCounters holds the cache statistics, updated atomically.
+type cacheCounters struct {
+	hits     uint64
+	misses   uint64
+	bypasses uint64
+}
+
+// CacheStats returns the statistics of the cache set with ClientParams.Cache.
+func (c *Client) CacheStats() CacheStats {
+	if c.cacheCounters == nil {
+		return CacheStats{}
+	}
+	return CacheStats{
+		Hits:     atomic.LoadUint64(&c.cacheCounters.hits),
+		Misses:   atomic.LoadUint64(&c.cacheCounters.misses),
+		Bypasses: atomic.LoadUint64(&c.cacheCounters.bypasses),
+	}
+}
+
+// cacheKey returns the cache key of a request, or "" if the request must
+// not be cached.
+func (c *Client) cacheKey(options *options, endpoint string, params interface{}) string {
+	if c.cache == nil {
+		return ""
+	}
+	key := ""
+	if !options.noCache {
+		key = requestKey(options, c.token, endpoint, params)
+	}
+	if key == "" {
+		atomic.AddUint64(&c.cacheCounters.bypasses, 1)
+	}
+	return key
+}
+
+// requestKey returns a hash identifying a deterministic request, or "" if
+// the request is not deterministic. Streams and async requests are never
+// deterministic. The token is part of the hash, so that clients of
+// different accounts sharing a cache never get each other's responses.
+func requestKey(options *options, token, endpoint string, params interface{}) string {
+	if options.url != "" || strings.Contains(options.rootURL, "/async/") || !isCacheable(params) {
+		return ""
+	}
+	j, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.New()
+	hash.Write([]byte(token))
+	hash.Write([]byte{0})
+	hash.Write([]byte(options.rootURL))
+	hash.Write([]byte{0})
+	hash.Write([]byte(endpoint))
+	hash.Write([]byte{0})
+	hash.Write(j)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isCacheable reports whether the response to params is deterministic.
+// Generations are only cached when sampling is disabled with a zero
+// temperature.
+func isCacheable(params interface{}) bool {
+	switch params := params.(type) {
+	case GenerationParams:
+		return params.Temperature != nil && *params.Temperature == 0
+	case AdGenerationParams, BatchGenerationParams, ChatbotParams, CodeGenerationParams, ImageGenerationParams, ParaphrasingParams:
+		return false
+	}
+	return true
+}
+
+// MemoryCache is an in-memory Cache evicting the least recently used
+// entries, and expiring entries after a TTL.
+type MemoryCache struct {
+	mu         sync.Mutex
+	maxEntries int
+	ttl        time.Duration
+	entries    map[string]*list.Element
+	lru        *list.List
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// Makes sure the *MemoryCache works with the Cache.
+var _ Cache = (*MemoryCache)(nil)
+
+// NewMemoryCache initializes a new MemoryCache holding up to maxEntries
+// entries for ttl. Zero values mean no limit.
+func NewMemoryCache(maxEntries int, ttl time.Duration) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		entries:    map[string]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Get returns the value stored for key, if any and not expired.
+func (m *MemoryCache) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*memoryEntry)
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		m.lru.Remove(elem)
+		delete(m.entries, key)
+		return nil, false
+	}
+	m.lru.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value for key, evicting the least recently used entry if the
+// cache is full.
+func (m *MemoryCache) Set(key string, value []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var expires time.Time
+	if m.ttl > 0 {
+		expires = time.Now().Add(m.ttl)
+	}
+	if elem, ok := m.entries[key]; ok {
+		entry := elem.Value.(*memoryEntry)
+		entry.value, entry.expires = value, expires
+		m.lru.MoveToFront(elem)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(&memoryEntry{key: key, value: value, expires: expires})
+	if m.maxEntries > 0 && m.lru.Len() > m.maxEntries {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache, including the expired
+// ones not evicted yet.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lru.Len()
+}
+
+// DiskCache is a Cache storing each entry in a file of a directory, and
+// expiring entries after a TTL.
+type DiskCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// Makes sure the *DiskCache works with the Cache.
+var _ Cache = (*DiskCache)(nil)
+
+// NewDiskCache initializes a new DiskCache in dir, creating it if needed.
+// Entries are kept for ttl. Zero means no expiry.
+func NewDiskCache(dir string, ttl time.Duration) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &DiskCache{dir: dir, ttl: ttl}, nil
+}
+
+// Get returns the value stored for key, if any and not expired.
+func (d *DiskCache) Get(key string) ([]byte, bool) {
+	path := d.path(key)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	if d.ttl > 0 && time.Since(info.ModTime()) > d.ttl {
+		os.Remove(path)
+		return nil, false
+	}
+	value, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// Set stores value for key. Errors are ignored, as the entry is simply
+// missing from the cache afterwards.
+func (d *DiskCache) Set(key string, value []byte) {
+	writeFileAtomic(d.path(key), value)
+}
+
+func (d *DiskCache) path(key string) string {
+	return filepath.Join(d.dir, filepath.Base(key))
+}
@@ -0,0 +1,287 @@
+package nlpcloud_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+func TestCacheHits(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert", Cache: nlpcloud.NewMemoryCache(0, 0)})
+
+	for i := 0; i < 3; i++ {
+		sentiment, err := client.Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sentiment.ScoredLabels) != 1 || sentiment.ScoredLabels[0].Label != "POSITIVE" {
+			t.Errorf("call %d: got %+v", i+1, sentiment)
+		}
+	}
+	if n := len(server.Requests()); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+	if stats := client.CacheStats(); stats != (nlpcloud.CacheStats{Hits: 2, Misses: 1}) {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	cache := nlpcloud.NewMemoryCache(0, 0)
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert", Cache: cache})
+
+	calls := []func(c *nlpcloud.Client) error{
+		func(c *nlpcloud.Client) error {
+			_, err := c.Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+			return err
+		},
+		func(c *nlpcloud.Client) error {
+			_, err := c.Sentiment(nlpcloud.SentimentParams{Text: "I hate it"})
+			return err
+		},
+		func(c *nlpcloud.Client) error {
+			_, err := c.Summarization(nlpcloud.SummarizationParams{Text: "I love it"})
+			return err
+		},
+		func(c *nlpcloud.Client) error {
+			_, err := server.NewClient(nlpcloud.ClientParams{Model: "roberta", Cache: cache}).Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+			return err
+		},
+		func(c *nlpcloud.Client) error {
+			_, err := server.NewClient(nlpcloud.ClientParams{Model: "distilbert", GPU: true, Cache: cache}).Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+			return err
+		},
+	}
+	for _, call := range calls {
+		for i := 0; i < 2; i++ {
+			if err := call(client); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if n := len(server.Requests()); n != len(calls) {
+		t.Errorf("got %d requests, want %d", n, len(calls))
+	}
+	if cache.Len() != len(calls) {
+		t.Errorf("got %d cache entries, want %d", cache.Len(), len(calls))
+	}
+}
+
+func TestCacheKeyToken(t *testing.T) {
+	// The server accepts any token
+	server := nlpcloudtest.NewServer("")
+	defer server.Close()
+	cache, err := nlpcloud.NewDiskCache(t.TempDir(), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"first-account", "second-account", "first-account"} {
+		client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert", Token: token, Cache: cache})
+		if _, err = client.Sentiment(nlpcloud.SentimentParams{Text: "I love it"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("got %d requests, want 1 per token", n)
+	}
+}
+
+func TestCacheBypass(t *testing.T) {
+	zero, sampled := 0.0, 0.7
+	tests := []struct {
+		name     string
+		params   nlpcloud.ClientParams
+		call     func(c *nlpcloud.Client) error
+		requests int
+		stats    nlpcloud.CacheStats
+	}{
+		{
+			name: "sampled generation",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Generation(nlpcloud.GenerationParams{Text: "text", Temperature: &sampled})
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+		{
+			name: "default generation",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Generation(nlpcloud.GenerationParams{Text: "text"})
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+		{
+			name: "greedy generation",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Generation(nlpcloud.GenerationParams{Text: "text", Temperature: &zero})
+				return err
+			},
+			requests: 1,
+			stats:    nlpcloud.CacheStats{Hits: 1, Misses: 1},
+		},
+		{
+			name: "chatbot",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Chatbot(nlpcloud.ChatbotParams{Input: "Hi"})
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+		{
+			name: "async job",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.AsyncSummarization(nlpcloud.SummarizationParams{Text: "text"})
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+		{
+			name:   "async client",
+			params: nlpcloud.ClientParams{Async: true},
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Summarization(nlpcloud.SummarizationParams{Text: "text"})
+				if _, ok := err.(*nlpcloud.AsyncAcceptedError); ok {
+					return nil
+				}
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+		{
+			name: "without cache",
+			call: func(c *nlpcloud.Client) error {
+				_, err := c.Sentiment(nlpcloud.SentimentParams{Text: "text"}, nlpcloud.WithoutCache())
+				return err
+			},
+			requests: 2,
+			stats:    nlpcloud.CacheStats{Bypasses: 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := nlpcloudtest.NewServer("token")
+			defer server.Close()
+			params := test.params
+			params.Model, params.Cache = "model", nlpcloud.NewMemoryCache(0, 0)
+			client := server.NewClient(params)
+
+			for i := 0; i < 2; i++ {
+				if err := test.call(client); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if n := len(server.Requests()); n != test.requests {
+				t.Errorf("got %d requests, want %d", n, test.requests)
+			}
+			if stats := client.CacheStats(); stats != test.stats {
+				t.Errorf("got stats %+v, want %+v", stats, test.stats)
+			}
+		})
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert", Cache: nlpcloud.NewMemoryCache(0, 0)})
+
+	server.Enqueue("sentiment", nlpcloudtest.ServerError(http.StatusInternalServerError))
+	if _, err := client.Sentiment(nlpcloud.SentimentParams{Text: "text"}); err == nil {
+		t.Fatal("got no error")
+	}
+	if _, err := client.Sentiment(nlpcloud.SentimentParams{Text: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+	if stats := client.CacheStats(); stats != (nlpcloud.CacheStats{Misses: 2}) {
+		t.Errorf("got stats %+v", stats)
+	}
+}
+
+func TestMemoryCacheLRU(t *testing.T) {
+	cache := nlpcloud.NewMemoryCache(2, 0)
+	cache.Set("a", []byte("1"))
+	cache.Set("b", []byte("2"))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	cache.Set("c", []byte("3"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("b was not evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("got %d entries, want 2", cache.Len())
+	}
+
+	// Setting an existing key refreshes it
+	cache.Set("a", []byte("4"))
+	cache.Set("d", []byte("5"))
+	if value, ok := cache.Get("a"); !ok || string(value) != "4" {
+		t.Errorf("got a = %q, %v", value, ok)
+	}
+	if _, ok := cache.Get("c"); ok {
+		t.Errorf("c was not evicted")
+	}
+}
+
+func TestMemoryCacheTTL(t *testing.T) {
+	cache := nlpcloud.NewMemoryCache(0, 50*time.Millisecond)
+	cache.Set("a", []byte("1"))
+	if _, ok := cache.Get("a"); !ok {
+		t.Fatal("a is missing")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("a did not expire")
+	}
+	if cache.Len() != 0 {
+		t.Errorf("got %d entries, want 0", cache.Len())
+	}
+}
+
+func TestDiskCacheTTL(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := nlpcloud.NewDiskCache(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Set("a", []byte("1"))
+	if value, ok := cache.Get("a"); !ok || string(value) != "1" {
+		t.Fatalf("got a = %q, %v", value, ok)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	if err = os.Chtimes(filepath.Join(dir, "a"), old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cache.Get("a"); ok {
+		t.Errorf("a did not expire")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "a")); !os.IsNotExist(err) {
+		t.Errorf("expired entry was not removed")
+	}
+}
@@ -0,0 +1,333 @@
+// Package cassette provides an nlpcloud.HTTPClient recording the interactions
+// with the NLP Cloud API into JSON cassette files, and replaying them
+// offline for deterministic tests.
+//
+//	recorder, err := cassette.New("testdata/summarization.json", cassette.ModeReplay, &http.Client{})
+//	if err != nil { ... }
+//	defer recorder.Save()
+//	client := nlpcloud.NewClient(recorder, nlpcloud.ClientParams{Model: "bart-large-cnn", Token: token})
+//
+// The Authorization header is never written to the cassettes.
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// ErrNoInteraction is returned in ModeReplay when no recorded interaction
+// matches a request.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+// ErrPendingInteraction is returned by Save when response bodies were
+// neither read entirely nor closed, so their interactions are not recorded
+// yet.
+var ErrPendingInteraction = errors.New("response body not read entirely nor closed")
+
+// Mode defines how a Recorder handles the requests.
+type Mode int
+
+const (
+	// ModeReplay replays the recorded interactions, and fails on requests
+	// that were not recorded.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records the interactions.
+	ModeRecord
+	// ModePassthrough sends the requests without recording them.
+	ModePassthrough
+)
+
+// Cassette holds recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction holds a request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request holds a recorded request.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response holds a recorded response.
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// Streamed is true if the body was streamed.
+	Streamed bool `json:"streamed,omitempty"`
+}
+
+// Recorder is an nlpcloud.HTTPClient recording or replaying interactions.
+// It is safe for concurrent use.
+type Recorder struct {
+	path   string
+	mode   Mode
+	client nlpcloud.HTTPClient
+
+	mu       sync.Mutex
+	cassette Cassette
+	replayed []bool
+	pending  int
+}
+
+// Makes sure the *Recorder works with the nlpcloud.HTTPClient.
+var _ nlpcloud.HTTPClient = (*Recorder)(nil)
+
+// New initializes a new Recorder for the cassette file at path. The file
+// is loaded in ModeReplay, and client sends the requests in ModeRecord and
+// ModePassthrough.
+func New(path string, mode Mode, client nlpcloud.HTTPClient) (*Recorder, error) {
+	r := &Recorder{
+		path:   path,
+		mode:   mode,
+		client: client,
+	}
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(data, &r.cassette); err != nil {
+			return nil, fmt.Errorf("invalid cassette %v: %w", path, err)
+		}
+		r.replayed = make([]bool, len(r.cassette.Interactions))
+	} else if client == nil {
+		return nil, errors.New("client is nil")
+	}
+	return r, nil
+}
+
+// Interactions returns the interactions recorded or loaded so far.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// Save writes the recorded interactions to the cassette file in
+// ModeRecord. It does nothing in the other modes.
+//
+// An interaction is recorded once its response body is read entirely or
+// closed. If some are still pending, the other interactions are written
+// and ErrPendingInteraction is returned: close the bodies and save again.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	pending := r.pending
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	if err = os.WriteFile(r.path, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	if pending > 0 {
+		return fmt.Errorf("%w: %d interactions not recorded", ErrPendingInteraction, pending)
+	}
+	return nil
+}
+
+// Do handles the request according to the Recorder mode.
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case ModeReplay:
+		return r.replay(req)
+	case ModeRecord:
+		return r.record(req)
+	default:
+		return r.client.Do(req)
+	}
+}
+
+// replay returns the response of the first interaction matching req that
+// was not replayed yet, or of the last matching one.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if err = req.Context().Err(); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !matches(interaction.Request, recorded) {
+			continue
+		}
+		found = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %v %v", ErrNoInteraction, recorded.Method, recorded.URL)
+	}
+	r.replayed[found] = true
+
+	resp := r.cassette.Interactions[found].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+		StatusCode:    resp.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        resp.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(resp.Body))),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// record sends req and records the interaction once the response body
+// is read entirely or closed.
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	recorded, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	r.pending++
+	r.mu.Unlock()
+	resp.Body = &recordingBody{
+		ReadCloser: resp.Body,
+		recorder:   r,
+		interaction: Interaction{
+			Request: recorded,
+			Response: Response{
+				Status:   resp.StatusCode,
+				Header:   resp.Header.Clone(),
+				Streamed: isStreaming(recorded.Body) || resp.Header.Get("Content-Type") == "text/event-stream",
+			},
+		},
+	}
+	return resp, nil
+}
+
+func (r *Recorder) add(interaction Interaction) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.pending--
+}
+
+// recordingBody copies the response body while it is read, and records the
+// interaction at the end.
+type recordingBody struct {
+	io.ReadCloser
+	recorder    *Recorder
+	interaction Interaction
+	buf         bytes.Buffer
+	once        sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err != nil {
+		b.done()
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	b.done()
+	return b.ReadCloser.Close()
+}
+
+func (b *recordingBody) done() {
+	b.once.Do(func() {
+		b.interaction.Response.Body = b.buf.String()
+		b.recorder.add(b.interaction)
+	})
+}
+
+// newRequest records req, restoring its body so it can still be sent.
+// The Authorization header is scrubbed.
+func newRequest(req *http.Request) (Request, error) {
+	recorded := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Header: req.Header.Clone(),
+	}
+	recorded.Header.Del("Authorization")
+	if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return Request{}, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		recorded.Body = string(body)
+	}
+	return recorded, nil
+}
+
+// matches reports whether the recorded request matches req by method, path
+// and normalized JSON body.
+func matches(recorded, req Request) bool {
+	return recorded.Method == req.Method &&
+		requestPath(recorded.URL) == requestPath(req.URL) &&
+		normalizeBody(recorded.Body) == normalizeBody(req.Body)
+}
+
+// requestPath returns the path and query of a URL.
+func requestPath(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.RequestURI()
+}
+
+// isStreaming reports whether the request body asks for a stream.
+func isStreaming(body string) bool {
+	var params struct {
+		Stream bool `json:"stream"`
+	}
+	return json.Unmarshal([]byte(body), &params) == nil && params.Stream
+}
+
+// normalizeBody returns the JSON body with sorted keys and no whitespace.
+// Other bodies are returned as is.
+func normalizeBody(body string) string {
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return body
+	}
+	normalized, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return string(normalized)
+}
@@ -0,0 +1,205 @@
+package cassette_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/cassette"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+// writeCassette writes a cassette file and returns its path.
+func writeCassette(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRecordReplay(t *testing.T) {
+	server := nlpcloudtest.NewServer("secret-token")
+	defer server.Close()
+	server.Enqueue("generation", nlpcloudtest.Stream("Hello", " wörld"))
+	path := filepath.Join(t.TempDir(), "testdata", "cassette.json")
+
+	recorder, err := cassette.New(path, cassette.ModeRecord, server.HTTPClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := nlpcloud.NewClient(recorder, nlpcloud.ClientParams{Model: "llama-3", Token: "secret-token"})
+	sentiment, err := client.Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := client.StreamingGeneration(nlpcloud.GenerationParams{Text: "Say hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := nlpcloud.NewStream(body).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret-token") || strings.Contains(string(data), "Authorization") {
+		t.Errorf("the cassette holds the Authorization header:\n%s", data)
+	}
+	interactions := recorder.Interactions()
+	if len(interactions) != 2 {
+		t.Fatalf("got %d interactions, want 2", len(interactions))
+	}
+	if interactions[0].Response.Streamed || !interactions[1].Response.Streamed {
+		t.Errorf("got streamed %v and %v, want false and true", interactions[0].Response.Streamed, interactions[1].Response.Streamed)
+	}
+	if want := "Hello\x00 wörld\x00[DONE]"; interactions[1].Response.Body != want {
+		t.Errorf("got streamed body %q, want %q", interactions[1].Response.Body, want)
+	}
+
+	// The interactions are replayed offline, whatever the token
+	replayer, err := cassette.New(path, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = nlpcloud.NewClient(replayer, nlpcloud.ClientParams{Model: "llama-3", Token: "other-token"})
+	replayed, err := client.Sentiment(nlpcloud.SentimentParams{Text: "I love it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replayed.ScoredLabels) != len(sentiment.ScoredLabels) {
+		t.Errorf("got replayed sentiment %+v, want %+v", replayed, sentiment)
+	}
+	body, err = client.StreamingGeneration(nlpcloud.GenerationParams{Text: "Say hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	if replayedText, err := nlpcloud.NewStream(body).Collect(); err != nil || replayedText != text {
+		t.Errorf("got replayed text %q, %v, want %q", replayedText, err, text)
+	}
+	if n := len(server.Requests()); n != 2 {
+		t.Errorf("got %d requests to the server, want 2", n)
+	}
+
+	if _, err = client.Sentiment(nlpcloud.SentimentParams{Text: "I hate it"}); !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("got error %v, want ErrNoInteraction", err)
+	}
+}
+
+func TestReplayMatching(t *testing.T) {
+	path := writeCassette(t, `{"interactions": [
+		{"request": {"method": "POST", "url": "https://api.nlpcloud.io/v1/distilbert/sentiment", "body": "{ \"text\": \"first\",\n \"options\": {\"b\": 1, \"a\": [1, 2]} }"},
+		 "response": {"status": 200, "body": "one"}},
+		{"request": {"method": "POST", "url": "https://api.nlpcloud.io/v1/distilbert/sentiment", "body": "{\"text\": \"first\"}"},
+		 "response": {"status": 200, "body": "two"}},
+		{"request": {"method": "POST", "url": "https://api.nlpcloud.io/v1/distilbert/sentiment", "body": "{\"text\": \"first\"}"},
+		 "response": {"status": 200, "body": "three"}}
+	]}`)
+	recorder, err := cassette.New(path, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	do := func(rawURL, body string) (string, error) {
+		req, err := http.NewRequest("POST", rawURL, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := recorder.Do(req)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		return string(data), err
+	}
+
+	tests := []struct {
+		name string
+		url  string
+		body string
+		want string
+		err  error
+	}{
+		{name: "normalized JSON", url: "https://api.nlpcloud.io/v1/distilbert/sentiment", body: `{"options":{"a":[1,2],"b":1},"text":"first"}`, want: "one"},
+		{name: "first not replayed", url: "https://api.nlpcloud.io/v1/distilbert/sentiment", body: `{"text":"first"}`, want: "two"},
+		{name: "next not replayed", url: "http://localhost/v1/distilbert/sentiment", body: `{ "text" : "first" }`, want: "three"},
+		{name: "last replayed again", url: "https://api.nlpcloud.io/v1/distilbert/sentiment", body: `{"text":"first"}`, want: "three"},
+		{name: "other body", url: "https://api.nlpcloud.io/v1/distilbert/sentiment", body: `{"text":"second"}`, err: cassette.ErrNoInteraction},
+		{name: "other path", url: "https://api.nlpcloud.io/v1/bart-large-cnn/sentiment", body: `{"text":"first"}`, err: cassette.ErrNoInteraction},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := do(test.url, test.body)
+			if !errors.Is(err, test.err) || got != test.want {
+				t.Errorf("got %q, %v, want %q, %v", got, err, test.want, test.err)
+			}
+		})
+	}
+}
+
+func TestSavePending(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := cassette.New(path, cassette.ModeRecord, server.HTTPClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := nlpcloud.NewClient(recorder, nlpcloud.ClientParams{Model: "llama-3", Token: "token"})
+
+	body, err := client.StreamingGeneration(nlpcloud.GenerationParams{Text: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); !errors.Is(err, cassette.ErrPendingInteraction) {
+		t.Errorf("got error %v, want ErrPendingInteraction", err)
+	}
+	body.Close()
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	replayer, err := cassette.New(path, cassette.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(replayer.Interactions()); n != 1 {
+		t.Errorf("got %d interactions saved, want 1", n)
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	recorder, err := cassette.New(path, cassette.ModePassthrough, server.HTTPClient())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := nlpcloud.NewClient(recorder, nlpcloud.ClientParams{Model: "distilbert", Token: "token"})
+	if _, err = client.Sentiment(nlpcloud.SentimentParams{Text: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = recorder.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.Interactions()) != 0 {
+		t.Errorf("got interactions in ModePassthrough")
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the cassette was written in ModePassthrough")
+	}
+}
@@ -0,0 +1,348 @@
+package nlpcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChatSessionParams wraps all the parameters for the ChatSession
+// initialization.
+type ChatSessionParams struct {
+	// Context is the context sent with each exchange.
+	Context string
+	// MaxTokens is the budget of the context, summary, history and input
+	// sent with each exchange. The oldest exchanges are dropped to fit, then
+	// the summary is truncated. Zero means no limit.
+	MaxTokens int
+	// CountTokens counts the tokens of a text. Default is EstimateTokens.
+	// Use utf8.RuneCountInString for a budget in characters.
+	CountTokens func(text string) int
+	// Summarize summarizes the dropped exchanges into the context instead
+	// of forgetting them. The client must implement Summarizer.
+	Summarize bool
+}
+
+// ChatSession is a conversation with Chatbot, keeping the history of the
+// exchanges. A ChatSession is safe for concurrent use: concurrent
+// exchanges each see the history as it was when they were sent.
+//
+// A ChatSession can be marshaled to JSON, and restored by unmarshaling
+// into a new session:
+//
+//	session := nlpcloud.NewChatSession(client, params)
+//	err := json.Unmarshal(stored, session)
+type ChatSession struct {
+	client Chatter
+	params ChatSessionParams
+
+	mu    sync.Mutex
+	state chatState
+	// version is the version of the session in its SessionStore.
+	version uint64
+	// trimMu serializes the summarizations of dropped exchanges.
+	trimMu sync.Mutex
+}
+
+// chatState holds the serialized state of a ChatSession.
+type chatState struct {
+	Context string     `json:"context,omitempty"`
+	Summary string     `json:"summary,omitempty"`
+	History []Exchange `json:"history"`
+}
+
+// NewChatSession initializes a new ChatSession with client. Streaming
+// requires the client to implement StreamingChatter.
+func NewChatSession(client Chatter, params ChatSessionParams) *ChatSession {
+	if params.CountTokens == nil {
+		params.CountTokens = EstimateTokens
+	}
+	return &ChatSession{
+		client: client,
+		params: params,
+		state:  chatState{Context: params.Context},
+	}
+}
+
+// Send sends input with the history, records the exchange, and returns
+// the response.
+func (s *ChatSession) Send(input string, opts ...Option) (string, error) {
+	params := s.prepare(input, opts)
+	chatbot, err := s.client.Chatbot(params, opts...)
+	if err != nil {
+		return "", err
+	}
+	s.record(Exchange{Input: input, Response: chatbot.Response})
+	return chatbot.Response, nil
+}
+
+// Stream sends input with the history, and returns the stream of the
+// response, to be read with NewStream. The exchange is recorded once the
+// stream is read entirely.
+func (s *ChatSession) Stream(input string, opts ...Option) (io.ReadCloser, error) {
+	streamer, ok := s.client.(StreamingChatter)
+	if !ok {
+		return nil, errors.New("the client does not support streaming")
+	}
+	params := s.prepare(input, opts)
+	body, err := streamer.StreamingChatbot(params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &chatStream{ReadCloser: body, session: s, input: input}, nil
+}
+
+// History returns the exchanges kept in the session.
+func (s *ChatSession) History() []Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exchange(nil), s.state.History...)
+}
+
+// Context returns the context of the session.
+func (s *ChatSession) Context() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Context
+}
+
+// SetContext sets the context of the session.
+func (s *ChatSession) SetContext(context string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Context = context
+}
+
+// Summary returns the summary of the dropped exchanges, if any.
+func (s *ChatSession) Summary() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Summary
+}
+
+// Reset forgets the history and the summary.
+func (s *ChatSession) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.History = nil
+	s.state.Summary = ""
+}
+
+// MarshalJSON encodes the context, summary and history of the session.
+func (s *ChatSession) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.state)
+}
+
+// UnmarshalJSON restores the context, summary and history of the session.
+func (s *ChatSession) UnmarshalJSON(data []byte) error {
+	var state chatState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = state
+	return nil
+}
+
+// Load restores the session id from store. A session not found in the
+// store is left empty, so it is created by Save.
+func (s *ChatSession) Load(ctx context.Context, store SessionStore, id string) error {
+	record, err := store.Load(ctx, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		s.mu.Lock()
+		s.state = chatState{Context: s.params.Context}
+		s.version = 0
+		s.mu.Unlock()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = chatState{Context: record.Context, Summary: record.Summary, History: record.History}
+	s.version = record.Version
+	return nil
+}
+
+// Save stores the session id in store. ErrSessionConflict is returned if
+// the session was saved by someone else since it was loaded; it must then
+// be loaded again.
+func (s *ChatSession) Save(ctx context.Context, store SessionStore, id string) error {
+	s.mu.Lock()
+	record := &SessionRecord{
+		Context: s.state.Context,
+		Summary: s.state.Summary,
+		History: append([]Exchange(nil), s.state.History...),
+		Version: s.version,
+	}
+	s.mu.Unlock()
+
+	if err := store.Save(ctx, id, record); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.version = record.Version
+	s.mu.Unlock()
+	return nil
+}
+
+// prepare fits the history in the budget, and returns the params of the
+// exchange.
+func (s *ChatSession) prepare(input string, opts []Option) ChatbotParams {
+	s.trim(input, opts)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	history := append([]Exchange{}, s.state.History...)
+	params := ChatbotParams{Input: input, History: &history}
+	if context := s.context(); context != "" {
+		params.Context = &context
+	}
+	return params
+}
+
+// context returns the context sent with the exchanges, including the
+// summary. s.mu must be held.
+func (s *ChatSession) context() string {
+	if s.state.Summary == "" {
+		return s.state.Context
+	}
+	if s.state.Context == "" {
+		return s.state.Summary
+	}
+	return s.state.Context + "\n\n" + s.state.Summary
+}
+
+// trim drops the oldest exchanges until the exchange of input fits in the
+// budget, and summarizes them if required. If the summarization fails, the
+// exchanges are dropped anyway.
+//
+// The budget is counted again with each new summary, which may be longer
+// than the exchanges it replaces: more exchanges are then dropped and
+// summarized, and once the history is empty, the summary is truncated.
+func (s *ChatSession) trim(input string, opts []Option) {
+	if s.params.MaxTokens <= 0 {
+		return
+	}
+	s.trimMu.Lock()
+	defer s.trimMu.Unlock()
+
+	summarizer, ok := s.client.(Summarizer)
+	summarize := ok && s.params.Summarize
+	count := s.params.CountTokens
+	for {
+		s.mu.Lock()
+		tokens := s.tokens(input)
+		drop := 0
+		for drop < len(s.state.History) && tokens > s.params.MaxTokens {
+			tokens -= count(s.state.History[drop].Input) + count(s.state.History[drop].Response)
+			drop++
+		}
+		dropped := s.state.History[:drop]
+		s.state.History = append([]Exchange(nil), s.state.History[drop:]...)
+		if tokens > s.params.MaxTokens {
+			s.truncateSummary(input)
+		}
+		summary := s.state.Summary
+		s.mu.Unlock()
+
+		if len(dropped) == 0 || !summarize {
+			return
+		}
+		text := []string{summary}
+		for _, exchange := range dropped {
+			text = append(text, exchange.Input+"\n"+exchange.Response)
+		}
+		summarization, err := summarizer.Summarization(SummarizationParams{Text: strings.TrimSpace(strings.Join(text, "\n\n"))}, opts...)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.state.Summary = summarization.SummaryText
+		s.mu.Unlock()
+	}
+}
+
+// tokens returns the tokens of the exchange of input. s.mu must be held.
+func (s *ChatSession) tokens(input string) int {
+	count := s.params.CountTokens
+	tokens := count(s.context()) + count(input)
+	for _, exchange := range s.state.History {
+		tokens += count(exchange.Input) + count(exchange.Response)
+	}
+	return tokens
+}
+
+// truncateSummary keeps the longest beginning of the summary, cut between
+// words, with which the exchange of input fits in the budget. s.mu must be
+// held.
+func (s *ChatSession) truncateSummary(input string) {
+	summary := s.state.Summary
+	words := splitWords(summary, segment{start: 0, end: len(summary)})
+	prefix := func(n int) string {
+		if n == 0 {
+			return ""
+		}
+		return summary[:words[n-1].end]
+	}
+	// Look for the first number of words that does not fit
+	n := sort.Search(len(words)+1, func(n int) bool {
+		s.state.Summary = prefix(n)
+		return s.tokens(input) > s.params.MaxTokens
+	})
+	if n > 0 {
+		n--
+	}
+	s.state.Summary = prefix(n)
+}
+
+// record appends an exchange to the history.
+func (s *ChatSession) record(exchange Exchange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.History = append(s.state.History, exchange)
+}
+
+// chatStream records the exchange of a stream once it is read entirely.
+type chatStream struct {
+	io.ReadCloser
+	session *ChatSession
+	input   string
+	body    bytes.Buffer
+	once    sync.Once
+}
+
+func (s *chatStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.body.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		s.record()
+	}
+	return n, err
+}
+
+func (s *chatStream) Close() error {
+	// The end marker may be read without reaching EOF
+	if bytes.Contains(s.body.Bytes(), []byte(streamEndMarker)) {
+		s.record()
+	}
+	return s.ReadCloser.Close()
+}
+
+func (s *chatStream) record() {
+	s.once.Do(func() {
+		response, err := NewStream(io.NopCloser(bytes.NewReader(s.body.Bytes()))).Collect()
+		if err == nil {
+			s.session.record(Exchange{Input: s.input, Response: response})
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package nlpcloud_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+// countWords counts the tokens of a text as its words.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestChatSessionBudget(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+	}{
+		{name: "short summary", summary: "the human said hello"},
+		// The summary is longer than the dropped exchanges, and than the
+		// budget
+		{name: "long summary", summary: strings.Repeat("the human and the AI talked a lot ", 8)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := nlpcloudtest.NewServer("token")
+			defer server.Close()
+			client := server.NewClient(nlpcloud.ClientParams{Model: "finetuned-llama-3-70b"})
+			server.Handle("chatbot", func(nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+				return nlpcloudtest.JSON(http.StatusOK, nlpcloud.Chatbot{Response: "this is a response of the AI with ten words"})
+			})
+			server.Handle("summarization", func(nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+				return nlpcloudtest.JSON(http.StatusOK, nlpcloud.Summarization{SummaryText: test.summary})
+			})
+
+			const maxTokens = 40
+			session := nlpcloud.NewChatSession(client, nlpcloud.ChatSessionParams{
+				Context:     "You are helpful.",
+				MaxTokens:   maxTokens,
+				CountTokens: countWords,
+				Summarize:   true,
+			})
+			for turn := 0; turn < 10; turn++ {
+				if _, err := session.Send("tell me something about the weather today"); err != nil {
+					t.Fatal(err)
+				}
+				req, ok := server.LastRequest()
+				if !ok || req.Endpoint != "chatbot" {
+					t.Fatalf("turn %d: got last request %+v, want a chatbot request", turn, req)
+				}
+				var params nlpcloud.ChatbotParams
+				if err := req.Decode(&params); err != nil {
+					t.Fatal(err)
+				}
+				tokens := countWords(params.Input)
+				if params.Context != nil {
+					tokens += countWords(*params.Context)
+				}
+				if params.History != nil {
+					for _, exchange := range *params.History {
+						tokens += countWords(exchange.Input) + countWords(exchange.Response)
+					}
+				}
+				if tokens > maxTokens {
+					t.Errorf("turn %d: sent %d tokens, want at most %d", turn, tokens, maxTokens)
+				}
+			}
+			if len(server.RequestsFor("summarization")) == 0 || session.Summary() == "" {
+				t.Errorf("got summary %q, want the dropped exchanges summarized", session.Summary())
+			}
+		})
+	}
+}
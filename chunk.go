@@ -0,0 +1,258 @@
+package nlpcloud
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ChunkParams wraps the parameters of the chunked batch requests.
+type ChunkParams struct {
+	// ChunkSize is the maximum number of items sent in a request.
+	// Default is 16.
+	ChunkSize int
+	// Concurrency is the maximum number of concurrent requests.
+	// Default is 4.
+	Concurrency int
+}
+
+// ItemFailure holds the failure of an item of a chunked batch request.
+type ItemFailure struct {
+	// Index is the index of the item in the input slice.
+	Index int
+	Err   error
+}
+
+// PartialResult reports the items of a chunked batch request that failed.
+// The results of the failed items are left empty.
+type PartialResult struct {
+	Failures []ItemFailure
+}
+
+// Err returns a *PartialError if some items failed, and nil otherwise.
+func (p PartialResult) Err() error {
+	if len(p.Failures) == 0 {
+		return nil
+	}
+	return &PartialError{Failures: p.Failures}
+}
+
+// PartialError is the error of a chunked batch request whose items partly
+// failed.
+type PartialError struct {
+	Failures []ItemFailure
+}
+
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("%d items failed, first error at index %d: %v", len(e.Failures), e.Failures[0].Index, e.Failures[0].Err)
+}
+
+// Unwrap returns the error of the first failed item.
+func (e *PartialError) Unwrap() error {
+	return e.Failures[0].Err
+}
+
+// ChunkedBatchClassification holds the results of a chunked batch
+// classification, with one score per text like BatchClassification.
+type ChunkedBatchClassification struct {
+	Scores []float64
+	PartialResult
+}
+
+// ChunkedBatchSummarization holds the results of a chunked batch
+// summarization.
+type ChunkedBatchSummarization struct {
+	SummaryTexts []string
+	PartialResult
+}
+
+// ChunkedBatchTranslation holds the results of a chunked batch translation.
+type ChunkedBatchTranslation struct {
+	TranslationTexts []string
+	PartialResult
+}
+
+// ChunkedEmbeddings holds the results of chunked embeddings.
+type ChunkedEmbeddings struct {
+	Embeddings [][]float64
+	PartialResult
+}
+
+// ChunkedBatchClassification classifies any number of texts, split in
+// chunks sent concurrently. The items of the failed chunks are reported in
+// the PartialResult. Labels must hold exactly one label, since the batch
+// endpoint returns one score per text.
+func (c *Client) ChunkedBatchClassification(params BatchClassificationParams, chunk ChunkParams, opts ...Option) (*ChunkedBatchClassification, error) {
+	if len(params.Labels) != 1 {
+		return nil, errors.New("labels must hold exactly one label")
+	}
+	result := &ChunkedBatchClassification{Scores: make([]float64, len(params.Texts))}
+	result.Failures = c.runChunks(len(params.Texts), chunk, opts, func(start, end int) error {
+		batch, err := c.BatchClassification(BatchClassificationParams{Texts: params.Texts[start:end], Labels: params.Labels}, opts...)
+		if err != nil {
+			return err
+		}
+		if len(batch.Scores) != end-start {
+			return errUnexpectedCount(len(batch.Scores), end-start)
+		}
+		copy(result.Scores[start:end], batch.Scores)
+		return nil
+	})
+	return result, nil
+}
+
+// ChunkedBatchSummarization summarizes any number of texts, split in
+// chunks sent concurrently. The items of the failed chunks are reported in
+// the PartialResult.
+func (c *Client) ChunkedBatchSummarization(params BatchSummarizationParams, chunk ChunkParams, opts ...Option) (*ChunkedBatchSummarization, error) {
+	result := &ChunkedBatchSummarization{SummaryTexts: make([]string, len(params.Texts))}
+	result.Failures = c.runChunks(len(params.Texts), chunk, opts, func(start, end int) error {
+		batch, err := c.BatchSummarization(BatchSummarizationParams{Texts: params.Texts[start:end], Size: params.Size}, opts...)
+		if err != nil {
+			return err
+		}
+		if len(batch.SummaryTexts) != end-start {
+			return errUnexpectedCount(len(batch.SummaryTexts), end-start)
+		}
+		copy(result.SummaryTexts[start:end], batch.SummaryTexts)
+		return nil
+	})
+	return result, nil
+}
+
+// ChunkedBatchTranslation translates any number of texts, split in chunks
+// sent concurrently. The items of the failed chunks are reported in the
+// PartialResult. Sources and Targets, if set, must hold one language per
+// text.
+func (c *Client) ChunkedBatchTranslation(params BatchTranslationParams, chunk ChunkParams, opts ...Option) (*ChunkedBatchTranslation, error) {
+	if params.Sources != nil && len(*params.Sources) != len(params.Texts) {
+		return nil, errors.New("sources and texts must have the same length")
+	}
+	if params.Targets != nil && len(*params.Targets) != len(params.Texts) {
+		return nil, errors.New("targets and texts must have the same length")
+	}
+
+	result := &ChunkedBatchTranslation{TranslationTexts: make([]string, len(params.Texts))}
+	result.Failures = c.runChunks(len(params.Texts), chunk, opts, func(start, end int) error {
+		chunkParams := BatchTranslationParams{Texts: params.Texts[start:end]}
+		if params.Sources != nil {
+			sources := (*params.Sources)[start:end]
+			chunkParams.Sources = &sources
+		}
+		if params.Targets != nil {
+			targets := (*params.Targets)[start:end]
+			chunkParams.Targets = &targets
+		}
+		batch, err := c.BatchTranslation(chunkParams, opts...)
+		if err != nil {
+			return err
+		}
+		if len(batch.TranslationTexts) != end-start {
+			return errUnexpectedCount(len(batch.TranslationTexts), end-start)
+		}
+		copy(result.TranslationTexts[start:end], batch.TranslationTexts)
+		return nil
+	})
+	return result, nil
+}
+
+// ChunkedEmbeddings extracts embeddings from any number of sentences,
+// split in chunks sent concurrently. The items of the failed chunks are
+// reported in the PartialResult.
+func (c *Client) ChunkedEmbeddings(params EmbeddingsParams, chunk ChunkParams, opts ...Option) (*ChunkedEmbeddings, error) {
+	result := &ChunkedEmbeddings{Embeddings: make([][]float64, len(params.Sentences))}
+	result.Failures = c.runChunks(len(params.Sentences), chunk, opts, func(start, end int) error {
+		embeddings, err := c.Embeddings(EmbeddingsParams{Sentences: params.Sentences[start:end]}, opts...)
+		if err != nil {
+			return err
+		}
+		if len(embeddings.Embeddings) != end-start {
+			return errUnexpectedCount(len(embeddings.Embeddings), end-start)
+		}
+		copy(result.Embeddings[start:end], embeddings.Embeddings)
+		return nil
+	})
+	return result, nil
+}
+
+// runChunks calls fn for the chunks [start, end) of n items, with bounded
+// concurrency, and returns the failed items sorted by index.
+//
+// A chunk rejected because of its content (too large or invalid) is split
+// in halves and retried, so the failures are narrowed down to the faulty
+// items.
+func (c *Client) runChunks(n int, params ChunkParams, opts []Option, fn func(start, end int) error) []ItemFailure {
+	if params.ChunkSize < 1 {
+		params.ChunkSize = 16
+	}
+	if params.Concurrency < 1 {
+		params.Concurrency = 4
+	}
+	ctx := c.newOptions(opts).Ctx
+
+	var (
+		mu       sync.Mutex
+		failures []ItemFailure
+		wg       sync.WaitGroup
+		sem      = make(chan struct{}, params.Concurrency)
+	)
+	fail := func(start, end int, err error) {
+		mu.Lock()
+		for i := start; i < end; i++ {
+			failures = append(failures, ItemFailure{Index: i, Err: err})
+		}
+		mu.Unlock()
+	}
+	var run func(start, end int)
+	run = func(start, end int) {
+		err := fn(start, end)
+		if err == nil {
+			return
+		}
+		if end-start > 1 && isSplittable(err) {
+			mid := start + (end-start)/2
+			run(start, mid)
+			run(mid, end)
+			return
+		}
+		fail(start, end, err)
+	}
+
+	for start := 0; start < n; start += params.ChunkSize {
+		end := start + params.ChunkSize
+		if end > n {
+			end = n
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			fail(start, n, ctx.Err())
+			wg.Wait()
+			return sortFailures(failures)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(start, end)
+		}(start, end)
+	}
+	wg.Wait()
+	return sortFailures(failures)
+}
+
+// isSplittable reports whether a chunk failed because of its content, and
+// may succeed in smaller parts.
+func isSplittable(err error) bool {
+	return errors.Is(err, ErrRequestTooLarge) || errors.Is(err, ErrBadRequest) || errors.Is(err, ErrUnprocessable)
+}
+
+func sortFailures(failures []ItemFailure) []ItemFailure {
+	sort.Slice(failures, func(i, j int) bool { return failures[i].Index < failures[j].Index })
+	return failures
+}
+
+func errUnexpectedCount(got, want int) error {
+	return fmt.Errorf("unexpected number of results: got %d, want %d", got, want)
+}
@@ -0,0 +1,103 @@
+package nlpcloud_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+func TestChunkedBatchClassification(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+
+	texts := []string{"a", "b", "c", "d", "e"}
+	result, err := client.ChunkedBatchClassification(
+		nlpcloud.BatchClassificationParams{Texts: texts, Labels: []string{"sport"}},
+		nlpcloud.ChunkParams{ChunkSize: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = result.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Scores) != len(texts) {
+		t.Errorf("got %d scores, want %d", len(result.Scores), len(texts))
+	}
+	requests := server.RequestsFor("batch-classification")
+	if len(requests) != 3 {
+		t.Errorf("got %d requests, want 3", len(requests))
+	}
+	for _, req := range requests {
+		var params nlpcloud.BatchClassificationParams
+		if err = req.Decode(&params); err != nil {
+			t.Fatal(err)
+		}
+		if len(params.Texts) > 2 || len(params.Labels) != 1 {
+			t.Errorf("got chunk %+v", params)
+		}
+	}
+}
+
+func TestChunkedBatchClassificationLabels(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+
+	// The scores of several labels could not be told apart
+	for _, labels := range [][]string{nil, {"sport", "politics"}} {
+		_, err := client.ChunkedBatchClassification(nlpcloud.BatchClassificationParams{Texts: []string{"a"}, Labels: labels}, nlpcloud.ChunkParams{})
+		if err == nil {
+			t.Errorf("labels %q: got no error", labels)
+		}
+	}
+	if n := len(server.Requests()); n != 0 {
+		t.Errorf("got %d requests, want 0", n)
+	}
+}
+
+func TestChunkedBatchClassificationScores(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-mnli"})
+
+	// Each text is scored with its position, and the chunk of "c" returns
+	// a score too many
+	server.Handle("batch-classification", func(req nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+		var params nlpcloud.BatchClassificationParams
+		req.Decode(&params)
+		var scores []float64
+		for _, text := range params.Texts {
+			scores = append(scores, float64(text[0]-'a'))
+			if text == "c" {
+				scores = append(scores, 0)
+			}
+		}
+		return nlpcloudtest.JSON(http.StatusOK, nlpcloud.BatchClassification{Scores: scores})
+	})
+
+	result, err := client.ChunkedBatchClassification(
+		nlpcloud.BatchClassificationParams{Texts: []string{"a", "b", "c", "d", "e"}, Labels: []string{"sport"}},
+		nlpcloud.ChunkParams{ChunkSize: 2},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{0, 1, 0, 0, 4}
+	for i, score := range result.Scores {
+		if score != want[i] {
+			t.Errorf("score %d: got %v, want %v", i, score, want[i])
+		}
+	}
+	if len(result.Failures) != 2 || result.Failures[0].Index != 2 || result.Failures[1].Index != 3 {
+		t.Fatalf("got failures %+v, want items 2 and 3", result.Failures)
+	}
+	var partial *nlpcloud.PartialError
+	if err = result.Err(); !errors.As(err, &partial) {
+		t.Errorf("got error %v, want *PartialError", err)
+	}
+}
@@ -8,30 +8,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"reflect"
 	"strings"
+	"sync/atomic"
 )
 
-// HTTPError is an error type returned when the HTTP request
-// is failing.
-type HTTPError struct {
-	Detail string
-	Status int
-}
-
-func (h HTTPError) Error() string {
-	return fmt.Sprintf("http error with status %d: %v", h.Status, h.Detail)
-}
-
-func (h HTTPError) GetDetail() string {
-	return h.Detail
-}
-
-func (h HTTPError) GetStatusCode() int {
-	return h.Status
-}
-
-var _ error = (*HTTPError)(nil)
-
 // HTTPClient defines what a HTTP client have to implement in order to get
 // used by the Client.
 type HTTPClient interface {
@@ -43,9 +24,19 @@ var _ HTTPClient = (*http.Client)(nil)
 
 // Client holds the necessary information to connect to API.
 type Client struct {
-	client  HTTPClient
-	rootURL string
-	token   string
+	client       HTTPClient
+	rootURL      string
+	asyncRootURL string
+	token        string
+	model        string
+	retry        *RetryPolicy
+	limiter      RateLimiter
+	middlewares  []Middleware
+	cache        Cache
+	// cacheCounters is allocated separately for the 64-bit alignment
+	// required by sync/atomic.
+	cacheCounters *cacheCounters
+	flights       *flightGroup
 }
 
 // ClientParams wraps all the parameters for the client initialization.
@@ -55,10 +46,51 @@ type ClientParams struct {
 	GPU   bool
 	Lang  string
 	Async bool
+	// Retry defines how failed requests are retried.
+	// Default is no retry.
+	Retry *RetryPolicy
+	// RateLimiter throttles the requests issued by the client.
+	// Default is no throttling.
+	RateLimiter RateLimiter
+	// Middlewares wrap each request issued by the client.
+	// The first middleware is the outermost one.
+	Middlewares []Middleware
+	// Cache stores the responses of deterministic requests, in order to
+	// avoid paying for identical requests. Streams, async requests and
+	// generations using sampling are never cached.
+	// Default is no cache.
+	Cache Cache
+	// CoalesceRequests makes identical concurrent requests share one
+	// round trip and its response. Only deterministic requests are
+	// coalesced (see Cache), and requests given their own RetryPolicy or
+	// RateLimiter are always sent on their own.
+	CoalesceRequests bool
 }
 
 // NewClient initializes a new Client.
 func NewClient(client HTTPClient, clientParams ClientParams) *Client {
+	var flights *flightGroup
+	if clientParams.CoalesceRequests {
+		flights = newFlightGroup()
+	}
+	return &Client{
+		client:        client,
+		rootURL:       buildRootURL(clientParams, clientParams.Async),
+		asyncRootURL:  buildRootURL(clientParams, true),
+		token:         clientParams.Token,
+		model:         clientParams.Model,
+		retry:         clientParams.Retry,
+		limiter:       clientParams.RateLimiter,
+		middlewares:   append([]Middleware(nil), clientParams.Middlewares...),
+		cache:         clientParams.Cache,
+		cacheCounters: &cacheCounters{},
+		flights:       flights,
+	}
+}
+
+// buildRootURL returns the root URL of the model, for synchronous or
+// asynchronous requests.
+func buildRootURL(clientParams ClientParams, async bool) string {
 	rootUrl := "https://api.nlpcloud.io/v1/"
 	if clientParams.Lang == "en" {
 		clientParams.Lang = ""
@@ -69,127 +101,238 @@ func NewClient(client HTTPClient, clientParams ClientParams) *Client {
 	if clientParams.GPU {
 		rootUrl += "gpu/"
 	}
-	if clientParams.Async {
+	if async {
 		rootUrl += "async/"
 	}
 	if clientParams.Lang != "" {
 		rootUrl += clientParams.Lang + "/"
 	}
 	rootUrl += clientParams.Model
-
-	return &Client{
-		client:  client,
-		rootURL: rootUrl,
-		token:   clientParams.Token,
-	}
+	return rootUrl
 }
 
 func (c *Client) issueRequest(method, endpoint string, params, dst interface{}, opts ...Option) error {
-	// Check the client is properly defined
-	if c.client == nil {
-		return errors.New("client is nil")
-	}
-
-	// Marshal the request body if needed (in most cases, for POST)
-	var buf io.Reader = nil
-	if params != nil {
-		j, err := json.Marshal(params)
-		if err != nil {
-			return err
+	// Apply the options
+	options := c.newOptions(opts)
+
+	// Look the response up in the cache
+	cacheKey := c.cacheKey(options, endpoint, params)
+	if cacheKey != "" {
+		if body, ok := c.cache.Get(cacheKey); ok && json.Unmarshal(body, dst) == nil {
+			atomic.AddUint64(&c.cacheCounters.hits, 1)
+			return nil
 		}
-		buf = bytes.NewBuffer(j)
+		atomic.AddUint64(&c.cacheCounters.misses, 1)
 	}
 
-	// Apply the options
-	options := &options{
-		Ctx: context.Background(),
+	// Issue the request, sharing it with identical concurrent requests
+	// if enabled
+	req := &Request{
+		Method:   method,
+		Endpoint: endpoint,
+		Params:   params,
+		Result:   dst,
 	}
-	for _, opt := range opts {
-		opt.apply(options)
+	var resp *Response
+	var err error
+	if flightKey := c.flightKey(options, endpoint, params); flightKey != "" {
+		// The shared request decodes into its own result
+		req.Result = reflect.New(reflect.TypeOf(dst).Elem()).Interface()
+		resp, err = c.flights.do(options.Ctx, flightKey, func(ctx context.Context) (*Response, error) {
+			flightOptions := *options
+			flightOptions.Ctx = ctx
+			return c.do(req, &flightOptions)
+		})
+	} else {
+		resp, err = c.do(req, options)
 	}
-
-	// Create the request backbone
-	req, err := http.NewRequestWithContext(options.Ctx, method, c.rootURL+"/"+endpoint, buf)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", "Token "+c.token)
-	req.Header.Set("User-Agent", "nlpcloud-go-client")
 
-	// Issue the request
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
+	// Requests accepted for asynchronous processing only return the URL
+	// of the result
+	if resp.StatusCode == http.StatusAccepted {
+		switch dst.(type) {
+		case *Async, *AsyncResult:
+		default:
+			async := &Async{}
+			if err = json.Unmarshal(resp.Body, async); err != nil {
+				return err
+			}
+			return &AsyncAcceptedError{URL: async.URL}
+		}
 	}
 
-	// Check for request failure
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return &HTTPError{
-			Detail: string(body),
-			Status: resp.StatusCode,
+	// The result may have been provided by a middleware or by a shared
+	// request, in which case each caller decodes its own copy
+	if resp.Result != nil && resp.Result != dst {
+		if len(bytes.TrimSpace(resp.Body)) == 0 {
+			return copyResult(dst, resp.Result)
+		}
+		if err = json.Unmarshal(resp.Body, dst); err != nil {
+			return err
 		}
 	}
 
-	// Unmarshal response
-	if err = json.Unmarshal(body, dst); err != nil {
-		return err
+	if cacheKey != "" && resp.StatusCode == http.StatusOK && resp.Body != nil {
+		c.cache.Set(cacheKey, resp.Body)
 	}
 
 	return nil
 }
 
 func (c *Client) issueStreamingRequest(method, endpoint string, params interface{}, opts ...Option) (io.ReadCloser, error) {
+	// Issue the request
+	resp, err := c.do(&Request{
+		Method:    method,
+		Endpoint:  endpoint,
+		Params:    params,
+		Streaming: true,
+	}, c.newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	if resp.Stream == nil {
+		return nil, errors.New("streaming response has no body")
+	}
+
+	return resp.Stream, nil
+}
+
+// flightKey returns the key shared by identical requests, or "" if the
+// request must not be coalesced. A shared round trip is sent with the
+// options of the first caller, so the requests with their own retry policy
+// or rate limiter are not coalesced.
+func (c *Client) flightKey(options *options, endpoint string, params interface{}) string {
+	if c.flights == nil || options.Retry != c.retry || options.limiter != nil {
+		return ""
+	}
+	return requestKey(options, c.token, endpoint, params)
+}
+
+// do issues a request through the middlewares, retrying according to the
+// options.
+func (c *Client) do(req *Request, options *options) (*Response, error) {
 	// Check the client is properly defined
 	if c.client == nil {
 		return nil, errors.New("client is nil")
 	}
 
+	req.URL = options.requestURL(req.Endpoint)
+	req.Model = c.model
+	req.ctx = options.Ctx
+
+	// Issue the request
+	return c.doWithRetry(req, options, c.handler())
+}
+
+// send is the Handler issuing a request with the HTTPClient. On failure,
+// the response body is read into the returned *HTTPError.
+func (c *Client) send(req *Request) (*Response, error) {
 	// Marshal the request body if needed (in most cases, for POST)
-	var buf io.Reader = nil
-	if params != nil {
-		j, err := json.Marshal(params)
+	var payload []byte
+	if req.Params != nil {
+		j, err := json.Marshal(req.Params)
 		if err != nil {
 			return nil, err
 		}
-		streamingPayload := strings.TrimSuffix(string(j), "}") + `,"stream":true}`
-		buf = bytes.NewBuffer([]byte(streamingPayload))
-	}
-
-	// Apply the options
-	options := &options{
-		Ctx: context.Background(),
-	}
-	for _, opt := range opts {
-		opt.apply(options)
+		if req.Streaming {
+			j = []byte(strings.TrimSuffix(string(j), "}") + `,"stream":true}`)
+		}
+		payload = j
 	}
 
 	// Create the request backbone
-	req, err := http.NewRequestWithContext(options.Ctx, method, c.rootURL+"/"+endpoint, buf)
+	httpReq, err := c.newRequest(req.Context(), req.Method, req.URL, payload)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Token "+c.token)
-	req.Header.Set("User-Agent", "nlpcloud-go-client")
+	for key, values := range req.Header {
+		httpReq.Header[key] = values
+	}
 
 	// Issue the request
-	resp, err := c.client.Do(req)
+	resp, err := c.client.Do(httpReq)
 	if err != nil {
 		return nil, err
 	}
+	response := &Response{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+	}
 
 	// Check for request failure
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return nil, &HTTPError{
-			Status: resp.StatusCode,
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		return nil, newHTTPError(resp, body)
+	}
+
+	if req.Streaming {
+		response.Stream = resp.Body
+		return response, nil
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	response.Body = body
+
+	// Unmarshal response, which is empty while an async result is pending
+	if req.Result != nil && len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, req.Result); err != nil {
+			return nil, err
 		}
+		response.Result = req.Result
 	}
 
-	return resp.Body, nil
+	return response, nil
+}
+
+// copyResult copies the value pointed to by src into dst, which must be
+// pointers to the same type.
+func copyResult(dst, src interface{}) error {
+	dstValue, srcValue := reflect.ValueOf(dst), reflect.ValueOf(src)
+	if dstValue.Kind() != reflect.Ptr || dstValue.Type() != srcValue.Type() || srcValue.IsNil() {
+		return fmt.Errorf("cannot use result of type %T as %T", src, dst)
+	}
+	dstValue.Elem().Set(srcValue.Elem())
+	return nil
+}
+
+// newRequest creates the request backbone. The payload is wrapped in a
+// new reader each time so the request can be issued again on retry.
+func (c *Client) newRequest(ctx context.Context, method, url string, payload []byte) (*http.Request, error) {
+	var buf io.Reader = nil
+	if payload != nil {
+		buf = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Token "+c.token)
+	req.Header.Set("User-Agent", "nlpcloud-go-client")
+	return req, nil
+}
+
+// newOptions applies opts over the client defaults.
+func (c *Client) newOptions(opts []Option) *options {
+	options := &options{
+		Ctx:     context.Background(),
+		Retry:   c.retry,
+		rootURL: c.rootURL,
+	}
+	for _, opt := range opts {
+		opt.apply(options)
+	}
+	return options
 }
 
 type Option interface {
@@ -197,7 +340,26 @@ type Option interface {
 }
 
 type options struct {
-	Ctx context.Context
+	Ctx   context.Context
+	Retry *RetryPolicy
+
+	// rootURL is the root URL of the model the request is issued to.
+	rootURL string
+	// url overrides the URL built out of rootURL and the endpoint.
+	url string
+	// noCache bypasses the client cache.
+	noCache bool
+	// limiter is waited on by each attempt, in addition to the client
+	// rate limiter.
+	limiter RateLimiter
+}
+
+// requestURL returns the URL of the request to endpoint.
+func (opts *options) requestURL(endpoint string) string {
+	if opts.url != "" {
+		return opts.url
+	}
+	return opts.rootURL + "/" + endpoint
 }
 
 type ctxOpt struct {
@@ -216,3 +378,65 @@ func WithContext(ctx context.Context) Option {
 		ctx: ctx,
 	}
 }
+
+type retryOpt struct {
+	policy RetryPolicy
+}
+
+func (opt retryOpt) apply(opts *options) {
+	policy := opt.policy
+	opts.Retry = &policy
+}
+
+// WithRetryPolicy returns an Option that defines the RetryPolicy
+// to use with issuing a request.
+// Default is the ClientParams.Retry policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return &retryOpt{
+		policy: policy,
+	}
+}
+
+type rootURLOpt struct {
+	rootURL string
+}
+
+func (opt rootURLOpt) apply(opts *options) {
+	opts.rootURL = opt.rootURL
+}
+
+type urlOpt struct {
+	url string
+}
+
+func (opt urlOpt) apply(opts *options) {
+	opts.url = opt.url
+}
+
+type noCacheOpt struct{}
+
+func (opt noCacheOpt) apply(opts *options) {
+	opts.noCache = true
+}
+
+// WithoutCache returns an Option that bypasses the cache set with
+// ClientParams.Cache when issuing a request.
+func WithoutCache() Option {
+	return &noCacheOpt{}
+}
+
+type rateLimiterOpt struct {
+	limiter RateLimiter
+}
+
+func (opt rateLimiterOpt) apply(opts *options) {
+	opts.limiter = opt.limiter
+}
+
+// WithRateLimiter returns an Option that defines a RateLimiter to wait on
+// when issuing a request, in addition to the ClientParams.RateLimiter.
+func WithRateLimiter(limiter RateLimiter) Option {
+	return &rateLimiterOpt{
+		limiter: limiter,
+	}
+}
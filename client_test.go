@@ -0,0 +1,274 @@
+package nlpcloud
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// clientCall calls a method of the Client.
+type clientCall func(c *Client, opts ...Option) error
+
+// clientCalls holds a call of each public method of the Client issuing a
+// request, by method name.
+var clientCalls = map[string]clientCall{
+	"AdGeneration": func(c *Client, opts ...Option) error {
+		_, err := c.AdGeneration(AdGenerationParams{}, opts...)
+		return err
+	},
+	"ASR": func(c *Client, opts ...Option) error {
+		_, err := c.ASR(ASRParams{}, opts...)
+		return err
+	},
+	"AsyncResult": func(c *Client, opts ...Option) error {
+		_, err := c.AsyncResult(AsyncResultParams{URL: "https://api.nlpcloud.io/v1/get-async-result/1"}, opts...)
+		return err
+	},
+	"Chatbot": func(c *Client, opts ...Option) error {
+		_, err := c.Chatbot(ChatbotParams{}, opts...)
+		return err
+	},
+	"StreamingChatbot": func(c *Client, opts ...Option) error {
+		stream, err := c.StreamingChatbot(ChatbotParams{}, opts...)
+		if err != nil {
+			return err
+		}
+		return stream.Close()
+	},
+	"Classification": func(c *Client, opts ...Option) error {
+		_, err := c.Classification(ClassificationParams{}, opts...)
+		return err
+	},
+	"BatchClassification": func(c *Client, opts ...Option) error {
+		_, err := c.BatchClassification(BatchClassificationParams{}, opts...)
+		return err
+	},
+	"CodeGeneration": func(c *Client, opts ...Option) error {
+		_, err := c.CodeGeneration(CodeGenerationParams{}, opts...)
+		return err
+	},
+	"Dependencies": func(c *Client, opts ...Option) error {
+		_, err := c.Dependencies(DependenciesParams{}, opts...)
+		return err
+	},
+	"Entities": func(c *Client, opts ...Option) error {
+		_, err := c.Entities(EntitiesParams{}, opts...)
+		return err
+	},
+	"Embeddings": func(c *Client, opts ...Option) error {
+		_, err := c.Embeddings(EmbeddingsParams{}, opts...)
+		return err
+	},
+	"Generation": func(c *Client, opts ...Option) error {
+		_, err := c.Generation(GenerationParams{}, opts...)
+		return err
+	},
+	"StreamingGeneration": func(c *Client, opts ...Option) error {
+		stream, err := c.StreamingGeneration(GenerationParams{}, opts...)
+		if err != nil {
+			return err
+		}
+		return stream.Close()
+	},
+	"BatchGeneration": func(c *Client, opts ...Option) error {
+		_, err := c.BatchGeneration(BatchGenerationParams{}, opts...)
+		return err
+	},
+	"GSCorrection": func(c *Client, opts ...Option) error {
+		_, err := c.GSCorrection(GSCorrectionParams{}, opts...)
+		return err
+	},
+	"ImageGeneration": func(c *Client, opts ...Option) error {
+		_, err := c.ImageGeneration(ImageGenerationParams{}, opts...)
+		return err
+	},
+	"IntentClassification": func(c *Client, opts ...Option) error {
+		_, err := c.IntentClassification(IntentClassificationParams{}, opts...)
+		return err
+	},
+	"KwKpExtraction": func(c *Client, opts ...Option) error {
+		_, err := c.KwKpExtraction(KwKpExtractionParams{}, opts...)
+		return err
+	},
+	"LangDetection": func(c *Client, opts ...Option) error {
+		_, err := c.LangDetection(LangDetectionParams{}, opts...)
+		return err
+	},
+	"Paraphrasing": func(c *Client, opts ...Option) error {
+		_, err := c.Paraphrasing(ParaphrasingParams{}, opts...)
+		return err
+	},
+	"Question": func(c *Client, opts ...Option) error {
+		_, err := c.Question(QuestionParams{}, opts...)
+		return err
+	},
+	"SemanticSearch": func(c *Client, opts ...Option) error {
+		_, err := c.SemanticSearch(SemanticSearchParams{}, opts...)
+		return err
+	},
+	"SemanticSimilarity": func(c *Client, opts ...Option) error {
+		_, err := c.SemanticSimilarity(SemanticSimilarityParams{}, opts...)
+		return err
+	},
+	"SentenceDependencies": func(c *Client, opts ...Option) error {
+		_, err := c.SentenceDependencies(SentenceDependenciesParams{}, opts...)
+		return err
+	},
+	"Sentiment": func(c *Client, opts ...Option) error {
+		_, err := c.Sentiment(SentimentParams{}, opts...)
+		return err
+	},
+	"SpeechSynthesis": func(c *Client, opts ...Option) error {
+		_, err := c.SpeechSynthesis(SpeechSynthesisParams{}, opts...)
+		return err
+	},
+	"Summarization": func(c *Client, opts ...Option) error {
+		_, err := c.Summarization(SummarizationParams{}, opts...)
+		return err
+	},
+	"BatchSummarization": func(c *Client, opts ...Option) error {
+		_, err := c.BatchSummarization(BatchSummarizationParams{}, opts...)
+		return err
+	},
+	"Tokens": func(c *Client, opts ...Option) error {
+		_, err := c.Tokens(TokensParams{}, opts...)
+		return err
+	},
+	"Translation": func(c *Client, opts ...Option) error {
+		_, err := c.Translation(TranslationParams{}, opts...)
+		return err
+	},
+	"BatchTranslation": func(c *Client, opts ...Option) error {
+		_, err := c.BatchTranslation(BatchTranslationParams{}, opts...)
+		return err
+	},
+	"AsyncASR": func(c *Client, opts ...Option) error {
+		_, err := c.AsyncASR(ASRParams{}, opts...)
+		return err
+	},
+	"AsyncGeneration": func(c *Client, opts ...Option) error {
+		_, err := c.AsyncGeneration(GenerationParams{}, opts...)
+		return err
+	},
+	"AsyncSummarization": func(c *Client, opts ...Option) error {
+		_, err := c.AsyncSummarization(SummarizationParams{}, opts...)
+		return err
+	},
+	"AsyncTranslation": func(c *Client, opts ...Option) error {
+		_, err := c.AsyncTranslation(TranslationParams{}, opts...)
+		return err
+	},
+}
+
+// clientMethod is a method of the Client declared in a source file.
+type clientMethod struct {
+	Name string
+	Doc  string
+	Decl *ast.FuncDecl
+}
+
+// parseClientMethods returns the public methods of the Client declared in
+// the given source file, selected by keep.
+func parseClientMethods(t *testing.T, filename string, keep func(name string) bool) []clientMethod {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var methods []clientMethod
+	for _, decl := range file.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || !fn.Name.IsExported() || !keep(fn.Name.Name) {
+			continue
+		}
+		star, ok := fn.Recv.List[0].Type.(*ast.StarExpr)
+		if !ok {
+			continue
+		}
+		if ident, ok := star.X.(*ast.Ident); !ok || ident.Name != "Client" {
+			continue
+		}
+		methods = append(methods, clientMethod{Name: fn.Name.Name, Doc: fn.Doc.Text(), Decl: fn})
+	}
+	return methods
+}
+
+// requestMethods returns the names of the methods of the Client issuing a
+// request: all the methods of api.go, and the Async methods of async.go.
+func requestMethods(t *testing.T) []string {
+	t.Helper()
+	var names []string
+	for _, method := range parseClientMethods(t, "api.go", func(string) bool { return true }) {
+		names = append(names, method.Name)
+	}
+	for _, method := range parseClientMethods(t, "async.go", func(name string) bool { return strings.HasPrefix(name, "Async") }) {
+		names = append(names, method.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type contextKey struct{}
+
+func TestContextPropagation(t *testing.T) {
+	methods := requestMethods(t)
+	for _, name := range methods {
+		call, ok := clientCalls[name]
+		if !ok {
+			t.Errorf("%s: missing from clientCalls", name)
+			continue
+		}
+
+		t.Run(name, func(t *testing.T) {
+			var seen []context.Context
+			client := NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+				seen = append(seen, req.Context())
+				if err := req.Context().Err(); err != nil {
+					return nil, err
+				}
+				if strings.HasPrefix(name, "Async") && name != "AsyncResult" {
+					return stubResponse(http.StatusAccepted, `{"url":"https://api.nlpcloud.io/v1/get-async-result/1"}`), nil
+				}
+				if strings.HasPrefix(name, "Streaming") {
+					return stubResponse(http.StatusOK, "[DONE]"), nil
+				}
+				return stubResponse(http.StatusOK, "{}"), nil
+			}), ClientParams{Model: "model", Token: "token"})
+
+			ctx := context.WithValue(context.Background(), contextKey{}, name)
+			if err := call(client, WithContext(ctx)); err != nil {
+				t.Fatal(err)
+			}
+			if len(seen) != 1 {
+				t.Fatalf("got %d requests, want 1", len(seen))
+			}
+			if got := seen[0].Value(contextKey{}); got != name {
+				t.Errorf("request context holds %v, want %q", got, name)
+			}
+
+			seen = nil
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			if err := call(client, WithContext(ctx)); !errors.Is(err, context.Canceled) {
+				t.Errorf("cancelled context: got error %v, want context.Canceled", err)
+			}
+			for _, got := range seen {
+				if got.Err() != context.Canceled {
+					t.Errorf("request context is not cancelled")
+				}
+			}
+		})
+	}
+
+	for name := range clientCalls {
+		if i := sort.SearchStrings(methods, name); i == len(methods) || methods[i] != name {
+			t.Errorf("%s: not a request method of the Client", name)
+		}
+	}
+}
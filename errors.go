@@ -0,0 +1,151 @@
+package nlpcloud
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors matching the HTTP status of an HTTPError.
+// They can be used with errors.Is:
+//
+//	if errors.Is(err, nlpcloud.ErrRateLimited) { ... }
+var (
+	ErrBadRequest         = errors.New("bad request")
+	ErrUnauthorized       = errors.New("unauthorized")
+	ErrPaymentRequired    = errors.New("payment required")
+	ErrForbidden          = errors.New("forbidden")
+	ErrModelNotFound      = errors.New("model not found")
+	ErrRequestTooLarge    = errors.New("request too large")
+	ErrUnprocessable      = errors.New("unprocessable entity")
+	ErrRateLimited        = errors.New("rate limited")
+	ErrServerError        = errors.New("server error")
+	ErrServerOverloaded   = errors.New("server overloaded")
+	ErrServerTimeout      = errors.New("server timeout")
+	errUnknownHTTPFailure = errors.New("unknown http failure")
+)
+
+// HTTPError is an error type returned when the HTTP request
+// is failing.
+type HTTPError struct {
+	// Detail is the "detail" field of the response body, or the raw
+	// response body when it cannot be decoded.
+	Detail string
+	Status int
+	// Body is the raw response body.
+	Body string
+	// RequestID is the request identifier sent back by the API, if any.
+	RequestID string
+	// RetryAfter is the delay set by the Retry-After header, if any.
+	RetryAfter time.Duration
+}
+
+// newHTTPError builds an HTTPError out of a failed response and its body.
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	httpErr := &HTTPError{
+		Detail: decodeDetail(body),
+		Status: resp.StatusCode,
+		Body:   string(body),
+	}
+	for _, header := range []string{"X-Request-Id", "Request-Id"} {
+		if id := resp.Header.Get(header); id != "" {
+			httpErr.RequestID = id
+			break
+		}
+	}
+	if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+		httpErr.RetryAfter = delay
+	}
+	return httpErr
+}
+
+// decodeDetail extracts the "detail" field of a JSON error body.
+// Structured details (e.g. validation errors) are kept as JSON.
+func decodeDetail(body []byte) string {
+	var payload struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || len(payload.Detail) == 0 {
+		return string(body)
+	}
+	var detail string
+	if err := json.Unmarshal(payload.Detail, &detail); err == nil {
+		return detail
+	}
+	return strings.TrimSpace(string(payload.Detail))
+}
+
+func (h HTTPError) Error() string {
+	return fmt.Sprintf("http error with status %d: %v", h.Status, h.Detail)
+}
+
+func (h HTTPError) GetDetail() string {
+	return h.Detail
+}
+
+func (h HTTPError) GetStatusCode() int {
+	return h.Status
+}
+
+// GetRequestID returns the request identifier sent back by the API, if any.
+func (h HTTPError) GetRequestID() string {
+	return h.RequestID
+}
+
+// GetRetryAfter returns the delay set by the Retry-After header, if any.
+func (h HTTPError) GetRetryAfter() time.Duration {
+	return h.RetryAfter
+}
+
+// IsRetryable reports whether the request may succeed if issued again.
+func (h HTTPError) IsRetryable() bool {
+	switch h.Status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Is makes errors.Is match the HTTPError with the sentinel error of its
+// status.
+func (h HTTPError) Is(target error) bool {
+	return target == statusError(h.Status)
+}
+
+// statusError returns the sentinel error matching an HTTP status.
+func statusError(status int) error {
+	switch status {
+	case http.StatusBadRequest:
+		return ErrBadRequest
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusPaymentRequired:
+		return ErrPaymentRequired
+	case http.StatusForbidden:
+		return ErrForbidden
+	case http.StatusNotFound:
+		return ErrModelNotFound
+	case http.StatusRequestEntityTooLarge:
+		return ErrRequestTooLarge
+	case http.StatusUnprocessableEntity:
+		return ErrUnprocessable
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusInternalServerError, http.StatusBadGateway:
+		return ErrServerError
+	case http.StatusServiceUnavailable:
+		return ErrServerOverloaded
+	case http.StatusGatewayTimeout:
+		return ErrServerTimeout
+	}
+	return errUnknownHTTPFailure
+}
+
+var _ error = (*HTTPError)(nil)
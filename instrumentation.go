@@ -0,0 +1,168 @@
+package nlpcloud
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CallEvent describes one attempt of a call to an API endpoint.
+type CallEvent struct {
+	Endpoint string
+	Model    string
+	GPU      bool
+	Async    bool
+	Lang     string
+	Attempt  int
+	// Streaming is true for StreamingGeneration and StreamingChatbot.
+	// The event of a stream is emitted once the stream is read entirely
+	// or closed.
+	Streaming bool
+	// Status is the HTTP status code, or 0 if no response was received.
+	Status int
+	Err    error
+	// RequestBytes is the size of the marshaled parameters.
+	RequestBytes int
+	// ResponseBytes is the size of the response body.
+	ResponseBytes int
+	Duration      time.Duration
+	// TimeToFirstToken is the delay before the first chunk of a stream.
+	TimeToFirstToken time.Duration
+	// InputTokens and OutputTokens are set for generation endpoints.
+	InputTokens  int
+	OutputTokens int
+}
+
+// Instrumenter receives an event for each call to an API endpoint.
+// It must be safe for concurrent use.
+type Instrumenter interface {
+	ObserveCall(event CallEvent)
+}
+
+// InstrumentationMiddleware returns a Middleware emitting a CallEvent to
+// instrumenter for each attempt of a request.
+func InstrumentationMiddleware(instrumenter Instrumenter) Middleware {
+	return func(next Handler) Handler {
+		return func(req *Request) (*Response, error) {
+			event := CallEvent{
+				Endpoint:  req.Endpoint,
+				Model:     req.Model,
+				Attempt:   req.Attempt,
+				Streaming: req.Streaming,
+			}
+			event.GPU, event.Async, event.Lang = parseRequestURL(req.URL, req.Model)
+			if req.Params != nil {
+				if j, err := json.Marshal(req.Params); err == nil {
+					event.RequestBytes = len(j)
+				}
+			}
+
+			start := time.Now()
+			resp, err := next(req)
+			event.Duration = time.Since(start)
+			event.Err = err
+
+			if err != nil {
+				var httpErr *HTTPError
+				if errors.As(err, &httpErr) {
+					event.Status = httpErr.Status
+					event.ResponseBytes = len(httpErr.Body)
+				}
+				instrumenter.ObserveCall(event)
+				return resp, err
+			}
+
+			event.Status = resp.StatusCode
+			if resp.Stream != nil {
+				resp.Stream = &instrumentedStream{
+					ReadCloser:   resp.Stream,
+					instrumenter: instrumenter,
+					event:        event,
+					start:        start,
+				}
+				return resp, nil
+			}
+
+			event.ResponseBytes = len(resp.Body)
+			switch result := resp.Result.(type) {
+			case *Generation:
+				event.InputTokens = result.NbInputTokens
+				event.OutputTokens = result.NbGeneratedTokens
+			case *BatchGeneration:
+				for _, generation := range result.Generations {
+					event.InputTokens += generation.NbInputTokens
+					event.OutputTokens += generation.NbGeneratedTokens
+				}
+			}
+			instrumenter.ObserveCall(event)
+			return resp, nil
+		}
+	}
+}
+
+// parseRequestURL extracts the GPU, async and language flags out of a
+// request URL, e.g. "https://api.nlpcloud.io/v1/gpu/async/fra_Latn/<model>/<endpoint>".
+// URLs which are not built out of the model, like the async result URLs,
+// have no flags.
+func parseRequestURL(rawURL, model string) (gpu, async bool, lang string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || model == "" {
+		return false, false, ""
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(u.Path, "/v1/"), "/"), "/")
+	if len(segments) < 2 || segments[len(segments)-2] != model {
+		return false, false, ""
+	}
+	for _, segment := range segments[:len(segments)-2] {
+		switch segment {
+		case "gpu":
+			gpu = true
+		case "async":
+			async = true
+		default:
+			lang = segment
+		}
+	}
+	return gpu, async, lang
+}
+
+// instrumentedStream emits the CallEvent of a stream once it is read
+// entirely or closed.
+type instrumentedStream struct {
+	io.ReadCloser
+	instrumenter Instrumenter
+	event        CallEvent
+	start        time.Time
+	once         sync.Once
+}
+
+func (s *instrumentedStream) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	if n > 0 && s.event.ResponseBytes == 0 {
+		s.event.TimeToFirstToken = time.Since(s.start)
+	}
+	s.event.ResponseBytes += n
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			s.event.Err = err
+		}
+		s.emit()
+	}
+	return n, err
+}
+
+func (s *instrumentedStream) Close() error {
+	s.emit()
+	return s.ReadCloser.Close()
+}
+
+func (s *instrumentedStream) emit() {
+	s.once.Do(func() {
+		s.event.Duration = time.Since(s.start)
+		s.instrumenter.ObserveCall(s.event)
+	})
+}
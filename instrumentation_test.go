@@ -0,0 +1,88 @@
+package nlpcloud
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestParseRequestURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		model string
+		gpu   bool
+		async bool
+		lang  string
+	}{
+		{url: "https://api.nlpcloud.io/v1/bart-large-cnn/summarization", model: "bart-large-cnn"},
+		{url: "https://api.nlpcloud.io/v1/gpu/bart-large-cnn/summarization", model: "bart-large-cnn", gpu: true},
+		{url: "https://api.nlpcloud.io/v1/gpu/async/fra_Latn/bart-large-cnn/summarization", model: "bart-large-cnn", gpu: true, async: true, lang: "fra_Latn"},
+		{url: "https://api.nlpcloud.io/v1/async/a/summarization", model: "a", async: true},
+		{url: "https://api.nlpcloud.io/v1/gpu/async/de/gpu/summarization", model: "gpu", gpu: true, async: true, lang: "de"},
+		{url: "https://api.nlpcloud.io/v1/get-async-result/1", model: "bart-large-cnn"},
+		{url: "https://api.nlpcloud.io/v1/get-async-result/1", model: "get-async-result"},
+		{url: "https://api.nlpcloud.io/v1/gpu/bart-large-cnn/summarization", model: ""},
+		{url: "://invalid", model: "bart-large-cnn"},
+	}
+	for _, test := range tests {
+		gpu, async, lang := parseRequestURL(test.url, test.model)
+		if gpu != test.gpu || async != test.async || lang != test.lang {
+			t.Errorf("parseRequestURL(%q, %q) = %v, %v, %q, want %v, %v, %q",
+				test.url, test.model, gpu, async, lang, test.gpu, test.async, test.lang)
+		}
+	}
+}
+
+// recordingInstrumenter records the events it receives.
+type recordingInstrumenter struct {
+	mu     sync.Mutex
+	events []CallEvent
+}
+
+// Makes sure the *recordingInstrumenter works with the Instrumenter.
+var _ Instrumenter = (*recordingInstrumenter)(nil)
+
+func (r *recordingInstrumenter) ObserveCall(event CallEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func TestInstrumentationAsyncResult(t *testing.T) {
+	instrumenter := &recordingInstrumenter{}
+	client := NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path == "/v1/get-async-result/1" {
+			return stubResponse(http.StatusAccepted, ""), nil
+		}
+		return stubResponse(http.StatusAccepted, `{"url":"https://api.nlpcloud.io/v1/get-async-result/1"}`), nil
+	}), ClientParams{
+		Model:       "1",
+		Token:       "token",
+		GPU:         true,
+		Lang:        "fra_Latn",
+		Middlewares: []Middleware{InstrumentationMiddleware(instrumenter)},
+	})
+
+	job, err := client.AsyncSummarization(SummarizationParams{Text: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = job.Poll(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CallEvent{
+		{Endpoint: "summarization", GPU: true, Async: true, Lang: "fra_Latn"},
+		{Endpoint: "async-result"},
+	}
+	if len(instrumenter.events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(instrumenter.events), len(want))
+	}
+	for i, event := range instrumenter.events {
+		if event.Endpoint != want[i].Endpoint || event.GPU != want[i].GPU || event.Async != want[i].Async || event.Lang != want[i].Lang {
+			t.Errorf("event %d: got %s GPU=%v Async=%v Lang=%q, want %s GPU=%v Async=%v Lang=%q", i,
+				event.Endpoint, event.GPU, event.Async, event.Lang,
+				want[i].Endpoint, want[i].GPU, want[i].Async, want[i].Lang)
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package nlpcloud
+
+import "io"
+
+// AdGenerator generates product descriptions and ads.
+type AdGenerator interface {
+	AdGeneration(params AdGenerationParams, opts ...Option) (*AdGeneration, error)
+}
+
+// Transcriber extracts text from audio files.
+type Transcriber interface {
+	ASR(params ASRParams, opts ...Option) (*ASR, error)
+}
+
+// AsyncResultGetter gets async results.
+type AsyncResultGetter interface {
+	AsyncResult(params AsyncResultParams, opts ...Option) (*AsyncResult, error)
+}
+
+// Chatter responds as a human.
+type Chatter interface {
+	Chatbot(params ChatbotParams, opts ...Option) (*Chatbot, error)
+}
+
+// StreamingChatter responds as a human, as a stream.
+type StreamingChatter interface {
+	StreamingChatbot(params ChatbotParams, opts ...Option) (io.ReadCloser, error)
+}
+
+// Classifier applies scored labels to blocks of text.
+type Classifier interface {
+	Classification(params ClassificationParams, opts ...Option) (*Classification, error)
+}
+
+// BatchClassifier classifies batches of blocks of text.
+type BatchClassifier interface {
+	BatchClassification(params BatchClassificationParams, opts ...Option) (*BatchClassification, error)
+}
+
+// CodeGenerator generates source code.
+type CodeGenerator interface {
+	CodeGeneration(params CodeGenerationParams, opts ...Option) (*CodeGeneration, error)
+}
+
+// DependencyParser gets POS dependencies from blocks of text.
+type DependencyParser interface {
+	Dependencies(params DependenciesParams, opts ...Option) (*Dependencies, error)
+	SentenceDependencies(params SentenceDependenciesParams, opts ...Option) (*SentenceDependencies, error)
+}
+
+// EntityExtractor extracts entities from blocks of text.
+type EntityExtractor interface {
+	Entities(params EntitiesParams, opts ...Option) (*Entities, error)
+}
+
+// Embedder extracts embeddings from lists of sentences.
+type Embedder interface {
+	Embeddings(params EmbeddingsParams, opts ...Option) (*Embeddings, error)
+}
+
+// Generator generates blocks of text.
+type Generator interface {
+	Generation(params GenerationParams, opts ...Option) (*Generation, error)
+}
+
+// StreamingGenerator generates blocks of text, as a stream.
+type StreamingGenerator interface {
+	StreamingGeneration(params GenerationParams, opts ...Option) (io.ReadCloser, error)
+}
+
+// BatchGenerator generates batches of blocks of text.
+type BatchGenerator interface {
+	BatchGeneration(params BatchGenerationParams, opts ...Option) (*BatchGeneration, error)
+}
+
+// GrammarCorrector corrects the grammar and spelling of blocks of text.
+type GrammarCorrector interface {
+	GSCorrection(params GSCorrectionParams, opts ...Option) (*GSCorrection, error)
+}
+
+// ImageGenerator generates images out of text instructions.
+type ImageGenerator interface {
+	ImageGeneration(params ImageGenerationParams, opts ...Option) (*ImageGeneration, error)
+}
+
+// IntentClassifier classifies the intent of blocks of text.
+type IntentClassifier interface {
+	IntentClassification(params IntentClassificationParams, opts ...Option) (*IntentClassification, error)
+}
+
+// KeywordExtractor extracts keywords and keyphrases from blocks of text.
+type KeywordExtractor interface {
+	KwKpExtraction(params KwKpExtractionParams, opts ...Option) (*KwKpExtraction, error)
+}
+
+// LanguageDetector detects the languages of texts.
+type LanguageDetector interface {
+	LangDetection(params LangDetectionParams, opts ...Option) (*LangDetection, error)
+}
+
+// Paraphraser paraphrases blocks of text.
+type Paraphraser interface {
+	Paraphrasing(params ParaphrasingParams, opts ...Option) (*Paraphrasing, error)
+}
+
+// QuestionAnswerer answers questions with a context.
+type QuestionAnswerer interface {
+	Question(params QuestionParams, opts ...Option) (*Question, error)
+}
+
+// SemanticSearcher performs semantic search on custom data.
+type SemanticSearcher interface {
+	SemanticSearch(params SemanticSearchParams, opts ...Option) (*SemanticSearch, error)
+}
+
+// SimilarityScorer calculates semantic similarity scores.
+type SimilarityScorer interface {
+	SemanticSimilarity(params SemanticSimilarityParams, opts ...Option) (*SemanticSimilarity, error)
+}
+
+// SentimentAnalyzer defines the sentiment of blocks of text.
+type SentimentAnalyzer interface {
+	Sentiment(params SentimentParams, opts ...Option) (*Sentiment, error)
+}
+
+// SpeechSynthesizer generates audio out of texts.
+type SpeechSynthesizer interface {
+	SpeechSynthesis(params SpeechSynthesisParams, opts ...Option) (*SpeechSynthesis, error)
+}
+
+// Summarizer summarizes blocks of text.
+type Summarizer interface {
+	Summarization(params SummarizationParams, opts ...Option) (*Summarization, error)
+}
+
+// BatchSummarizer summarizes batches of blocks of text.
+type BatchSummarizer interface {
+	BatchSummarization(params BatchSummarizationParams, opts ...Option) (*BatchSummarization, error)
+}
+
+// Tokenizer tokenizes and lemmatizes texts.
+type Tokenizer interface {
+	Tokens(params TokensParams, opts ...Option) (*Tokens, error)
+}
+
+// Translator translates blocks of text.
+type Translator interface {
+	Translation(params TranslationParams, opts ...Option) (*Translation, error)
+}
+
+// BatchTranslator translates batches of blocks of text.
+type BatchTranslator interface {
+	BatchTranslation(params BatchTranslationParams, opts ...Option) (*BatchTranslation, error)
+}
+
+// Makes sure the *Client works with every capability.
+var (
+	_ AdGenerator        = (*Client)(nil)
+	_ Transcriber        = (*Client)(nil)
+	_ AsyncResultGetter  = (*Client)(nil)
+	_ Chatter            = (*Client)(nil)
+	_ StreamingChatter   = (*Client)(nil)
+	_ Classifier         = (*Client)(nil)
+	_ BatchClassifier    = (*Client)(nil)
+	_ CodeGenerator      = (*Client)(nil)
+	_ DependencyParser   = (*Client)(nil)
+	_ EntityExtractor    = (*Client)(nil)
+	_ Embedder           = (*Client)(nil)
+	_ Generator          = (*Client)(nil)
+	_ StreamingGenerator = (*Client)(nil)
+	_ BatchGenerator     = (*Client)(nil)
+	_ GrammarCorrector   = (*Client)(nil)
+	_ ImageGenerator     = (*Client)(nil)
+	_ IntentClassifier   = (*Client)(nil)
+	_ KeywordExtractor   = (*Client)(nil)
+	_ LanguageDetector   = (*Client)(nil)
+	_ Paraphraser        = (*Client)(nil)
+	_ QuestionAnswerer   = (*Client)(nil)
+	_ SemanticSearcher   = (*Client)(nil)
+	_ SimilarityScorer   = (*Client)(nil)
+	_ SentimentAnalyzer  = (*Client)(nil)
+	_ SpeechSynthesizer  = (*Client)(nil)
+	_ Summarizer         = (*Client)(nil)
+	_ BatchSummarizer    = (*Client)(nil)
+	_ Tokenizer          = (*Client)(nil)
+	_ Translator         = (*Client)(nil)
+	_ BatchTranslator    = (*Client)(nil)
+)
@@ -0,0 +1,222 @@
+package nlpcloud
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDurationBuckets are the upper bounds, in seconds, of the duration
+// histograms of Metrics.
+var DefaultDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Metrics is an Instrumenter aggregating the calls into counters and
+// histograms, exposed in the Prometheus text format:
+//
+//	metrics := nlpcloud.NewMetrics()
+//	client.Use(nlpcloud.InstrumentationMiddleware(metrics))
+//	http.Handle("/metrics", metrics)
+type Metrics struct {
+	mu         sync.Mutex
+	buckets    []float64
+	requests   map[string]float64
+	tokens     map[string]float64
+	bytes      map[string]float64
+	durations  map[string]*histogram
+	firstToken map[string]*histogram
+}
+
+// Makes sure the *Metrics works with the Instrumenter and http.Handler.
+var (
+	_ Instrumenter = (*Metrics)(nil)
+	_ http.Handler = (*Metrics)(nil)
+)
+
+// NewMetrics initializes a new Metrics using DefaultDurationBuckets.
+func NewMetrics() *Metrics {
+	return NewMetricsWithBuckets(DefaultDurationBuckets)
+}
+
+// NewMetricsWithBuckets initializes a new Metrics using the given duration
+// histogram upper bounds, in seconds.
+func NewMetricsWithBuckets(buckets []float64) *Metrics {
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets:    buckets,
+		requests:   map[string]float64{},
+		tokens:     map[string]float64{},
+		bytes:      map[string]float64{},
+		durations:  map[string]*histogram{},
+		firstToken: map[string]*histogram{},
+	}
+}
+
+// ObserveCall records a call.
+func (m *Metrics) ObserveCall(event CallEvent) {
+	base := [][2]string{{"endpoint", event.Endpoint}, {"model", event.Model}}
+	status := "error"
+	if event.Status != 0 {
+		status = strconv.Itoa(event.Status)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[formatLabels(append(base, [2]string{"status", status}))]++
+	m.observe(m.durations, formatLabels(base), event.Duration)
+	if event.Streaming && event.TimeToFirstToken > 0 {
+		m.observe(m.firstToken, formatLabels(base), event.TimeToFirstToken)
+	}
+	if event.RequestBytes > 0 {
+		m.bytes[formatLabels(append(base, [2]string{"direction", "request"}))] += float64(event.RequestBytes)
+	}
+	if event.ResponseBytes > 0 {
+		m.bytes[formatLabels(append(base, [2]string{"direction", "response"}))] += float64(event.ResponseBytes)
+	}
+	if event.InputTokens > 0 {
+		m.tokens[formatLabels(append(base, [2]string{"direction", "input"}))] += float64(event.InputTokens)
+	}
+	if event.OutputTokens > 0 {
+		m.tokens[formatLabels(append(base, [2]string{"direction", "output"}))] += float64(event.OutputTokens)
+	}
+}
+
+func (m *Metrics) observe(histograms map[string]*histogram, labels string, d time.Duration) {
+	h, ok := histograms[labels]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		histograms[labels] = h
+	}
+	h.observe(m.buckets, d.Seconds())
+}
+
+// WriteTo writes the metrics to w in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	writeCounter(cw, "nlpcloud_requests_total", "Number of calls to the NLP Cloud API.", m.requests)
+	writeHistogram(cw, "nlpcloud_request_duration_seconds", "Duration of the calls to the NLP Cloud API.", m.buckets, m.durations)
+	writeHistogram(cw, "nlpcloud_stream_time_to_first_token_seconds", "Delay before the first chunk of the NLP Cloud API streams.", m.buckets, m.firstToken)
+	writeCounter(cw, "nlpcloud_tokens_total", "Number of tokens processed by the NLP Cloud API.", m.tokens)
+	writeCounter(cw, "nlpcloud_bytes_total", "Number of bytes exchanged with the NLP Cloud API.", m.bytes)
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP serves the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_, _ = m.WriteTo(w)
+}
+
+// histogram holds the non-cumulative bucket counts of observations.
+type histogram struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(buckets []float64, value float64) {
+	h.count++
+	h.sum += value
+	for i, bound := range buckets {
+		if value <= bound {
+			h.counts[i]++
+			return
+		}
+	}
+}
+
+// formatLabels formats label pairs as `{name="value",...}`.
+func formatLabels(labels [][2]string) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(label[0])
+		sb.WriteString(`="`)
+		sb.WriteString(labelEscaper.Replace(label[1]))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+// withLabel appends a label to labels formatted by formatLabels.
+func withLabel(labels, name, value string) string {
+	return strings.TrimSuffix(labels, "}") + "," + formatLabels([][2]string{{name, value}})[1:]
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]float64:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*histogram:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeCounter(w io.Writer, name, help string, values map[string]float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, labels := range sortedKeys(values) {
+		fmt.Fprintf(w, "%s%s %s\n", name, labels, formatFloat(values[labels]))
+	}
+}
+
+func writeHistogram(w io.Writer, name, help string, buckets []float64, histograms map[string]*histogram) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for _, labels := range sortedKeys(histograms) {
+		h := histograms[labels]
+		var cumulative uint64
+		for i, bound := range buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatFloat(bound)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), h.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(h.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the bytes written and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
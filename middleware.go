@@ -0,0 +1,92 @@
+package nlpcloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Request describes a call to an API endpoint, as seen by the middlewares.
+type Request struct {
+	Method   string
+	Endpoint string
+	// URL is the full URL the request is sent to.
+	URL string
+	// Model is the model the client was initialized with.
+	Model string
+	// Params holds the endpoint parameters (e.g. SummarizationParams).
+	// It is marshaled for each attempt, so middlewares may replace it.
+	Params interface{}
+	// Attempt is the attempt number, starting at 1.
+	Attempt int
+	// Streaming is true for StreamingGeneration and StreamingChatbot.
+	Streaming bool
+	// Header holds additional headers sent with the request.
+	Header http.Header
+	// Result is the value the response is decoded into (e.g. *Summarization).
+	// It is nil for streaming requests.
+	Result interface{}
+
+	ctx context.Context
+}
+
+// Context returns the context of the request.
+func (r *Request) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// WithContext returns a shallow copy of the request with its context
+// changed to ctx.
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r2 := *r
+	r2.ctx = ctx
+	return &r2
+}
+
+// Response holds the successful response of a call to an API endpoint.
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	// Body is the raw response body. It is nil for streaming requests.
+	Body []byte
+	// Result is the decoded response body (e.g. *Summarization).
+	// It is nil for streaming requests.
+	Result interface{}
+	// Stream is the response body of streaming requests, to be closed by
+	// the caller.
+	Stream io.ReadCloser
+}
+
+// Handler issues a request and returns its response. Failures from the API
+// are returned as an *HTTPError.
+type Handler func(req *Request) (*Response, error)
+
+// Middleware wraps a Handler in order to observe or mutate the requests and
+// responses. A middleware is called for each attempt of a request.
+//
+//	logging := func(next nlpcloud.Handler) nlpcloud.Handler {
+//		return func(req *nlpcloud.Request) (*nlpcloud.Response, error) {
+//			resp, err := next(req)
+//			log.Println(req.Endpoint, req.Attempt, err)
+//			return resp, err
+//		}
+//	}
+type Middleware func(next Handler) Handler
+
+// Use registers middlewares on the client. The first middleware is the
+// outermost one. Use must not be called concurrently with requests.
+func (c *Client) Use(middlewares ...Middleware) {
+	c.middlewares = append(c.middlewares, middlewares...)
+}
+
+// handler returns the client handler wrapped by the middlewares.
+func (c *Client) handler() Handler {
+	h := Handler(c.send)
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
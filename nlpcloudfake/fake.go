@@ -0,0 +1,123 @@
+// Package nlpcloudfake provides an in-memory fake of the nlpcloud Client,
+// implementing every capability interface (nlpcloud.Summarizer,
+// nlpcloud.Translator, ...), with call recording and scripted responses.
+//
+//	fake := &nlpcloudfake.Fake{}
+//	fake.Script("Summarization", &nlpcloud.Summarization{SummaryText: "summary"}, nil)
+//	fake.Script("Summarization", nil, nlpcloud.ErrRateLimited)
+//	var summarizer nlpcloud.Summarizer = fake
+//
+// The zero value is ready to use. Unscripted calls return an empty result.
+package nlpcloudfake
+
+//go:generate go run gen.go
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// Call holds a call received by the Fake.
+type Call struct {
+	// Method is the method name (e.g. "Summarization").
+	Method string
+	// Params holds the method parameters (e.g. nlpcloud.SummarizationParams).
+	Params  interface{}
+	Options []nlpcloud.Option
+}
+
+// HandlerFunc builds the result of a call out of its parameters.
+type HandlerFunc func(params interface{}) (interface{}, error)
+
+type result struct {
+	value interface{}
+	err   error
+}
+
+// Fake is an in-memory fake of the nlpcloud Client.
+// It is safe for concurrent use.
+type Fake struct {
+	mu       sync.Mutex
+	calls    []Call
+	scripts  map[string][]result
+	handlers map[string]HandlerFunc
+}
+
+// Script enqueues a result to be returned once by the next call to method.
+// The value must have the result type of the method (e.g.
+// *nlpcloud.Summarization), or be nil.
+func (f *Fake) Script(method string, value interface{}, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.scripts == nil {
+		f.scripts = map[string][]result{}
+	}
+	f.scripts[method] = append(f.scripts[method], result{value: value, err: err})
+}
+
+// Handle sets the handler of method, used once the scripted results are
+// consumed. A nil handler restores the empty results.
+func (f *Fake) Handle(method string, handler HandlerFunc) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.handlers == nil {
+		f.handlers = map[string]HandlerFunc{}
+	}
+	if handler == nil {
+		delete(f.handlers, method)
+		return
+	}
+	f.handlers[method] = handler
+}
+
+// Calls returns the calls received so far.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls to method received so far.
+func (f *Fake) CallsTo(method string) []Call {
+	var calls []Call
+	for _, call := range f.Calls() {
+		if call.Method == method {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// Reset forgets the calls, the scripted results and the handlers.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.scripts = nil
+	f.handlers = nil
+}
+
+// call records a call and returns its scripted or handled result.
+func (f *Fake) call(method string, params interface{}, opts []nlpcloud.Option) (interface{}, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, Call{Method: method, Params: params, Options: opts})
+	if script := f.scripts[method]; len(script) > 0 {
+		f.scripts[method] = script[1:]
+		f.mu.Unlock()
+		return script[0].value, script[0].err
+	}
+	handler := f.handlers[method]
+	f.mu.Unlock()
+
+	if handler != nil {
+		return handler(params)
+	}
+	return nil, nil
+}
+
+// unexpectedType returns the error of a scripted value of the wrong type.
+func unexpectedType(method string, value interface{}) error {
+	return fmt.Errorf("nlpcloudfake: unexpected result of type %T for %v", value, method)
+}
@@ -0,0 +1,540 @@
+// Code generated by gen.go; DO NOT EDIT.
+
+package nlpcloudfake
+
+import (
+	"io"
+	"strings"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// AdGeneration records the call and returns the next scripted result.
+func (f *Fake) AdGeneration(params nlpcloud.AdGenerationParams, opts ...nlpcloud.Option) (*nlpcloud.AdGeneration, error) {
+	value, err := f.call("AdGeneration", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.AdGeneration{}, nil
+	}
+	result, ok := value.(*nlpcloud.AdGeneration)
+	if !ok {
+		return nil, unexpectedType("AdGeneration", value)
+	}
+	return result, nil
+}
+
+// ASR records the call and returns the next scripted result.
+func (f *Fake) ASR(params nlpcloud.ASRParams, opts ...nlpcloud.Option) (*nlpcloud.ASR, error) {
+	value, err := f.call("ASR", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.ASR{}, nil
+	}
+	result, ok := value.(*nlpcloud.ASR)
+	if !ok {
+		return nil, unexpectedType("ASR", value)
+	}
+	return result, nil
+}
+
+// AsyncResult records the call and returns the next scripted result.
+func (f *Fake) AsyncResult(params nlpcloud.AsyncResultParams, opts ...nlpcloud.Option) (*nlpcloud.AsyncResult, error) {
+	value, err := f.call("AsyncResult", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.AsyncResult{}, nil
+	}
+	result, ok := value.(*nlpcloud.AsyncResult)
+	if !ok {
+		return nil, unexpectedType("AsyncResult", value)
+	}
+	return result, nil
+}
+
+// Chatbot records the call and returns the next scripted result.
+func (f *Fake) Chatbot(params nlpcloud.ChatbotParams, opts ...nlpcloud.Option) (*nlpcloud.Chatbot, error) {
+	value, err := f.call("Chatbot", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Chatbot{}, nil
+	}
+	result, ok := value.(*nlpcloud.Chatbot)
+	if !ok {
+		return nil, unexpectedType("Chatbot", value)
+	}
+	return result, nil
+}
+
+// StreamingChatbot records the call and returns the next scripted result.
+func (f *Fake) StreamingChatbot(params nlpcloud.ChatbotParams, opts ...nlpcloud.Option) (io.ReadCloser, error) {
+	value, err := f.call("StreamingChatbot", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	result, ok := value.(io.ReadCloser)
+	if !ok {
+		return nil, unexpectedType("StreamingChatbot", value)
+	}
+	return result, nil
+}
+
+// Classification records the call and returns the next scripted result.
+func (f *Fake) Classification(params nlpcloud.ClassificationParams, opts ...nlpcloud.Option) (*nlpcloud.Classification, error) {
+	value, err := f.call("Classification", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Classification{}, nil
+	}
+	result, ok := value.(*nlpcloud.Classification)
+	if !ok {
+		return nil, unexpectedType("Classification", value)
+	}
+	return result, nil
+}
+
+// BatchClassification records the call and returns the next scripted result.
+func (f *Fake) BatchClassification(params nlpcloud.BatchClassificationParams, opts ...nlpcloud.Option) (*nlpcloud.BatchClassification, error) {
+	value, err := f.call("BatchClassification", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.BatchClassification{}, nil
+	}
+	result, ok := value.(*nlpcloud.BatchClassification)
+	if !ok {
+		return nil, unexpectedType("BatchClassification", value)
+	}
+	return result, nil
+}
+
+// CodeGeneration records the call and returns the next scripted result.
+func (f *Fake) CodeGeneration(params nlpcloud.CodeGenerationParams, opts ...nlpcloud.Option) (*nlpcloud.CodeGeneration, error) {
+	value, err := f.call("CodeGeneration", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.CodeGeneration{}, nil
+	}
+	result, ok := value.(*nlpcloud.CodeGeneration)
+	if !ok {
+		return nil, unexpectedType("CodeGeneration", value)
+	}
+	return result, nil
+}
+
+// Dependencies records the call and returns the next scripted result.
+func (f *Fake) Dependencies(params nlpcloud.DependenciesParams, opts ...nlpcloud.Option) (*nlpcloud.Dependencies, error) {
+	value, err := f.call("Dependencies", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Dependencies{}, nil
+	}
+	result, ok := value.(*nlpcloud.Dependencies)
+	if !ok {
+		return nil, unexpectedType("Dependencies", value)
+	}
+	return result, nil
+}
+
+// Entities records the call and returns the next scripted result.
+func (f *Fake) Entities(params nlpcloud.EntitiesParams, opts ...nlpcloud.Option) (*nlpcloud.Entities, error) {
+	value, err := f.call("Entities", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Entities{}, nil
+	}
+	result, ok := value.(*nlpcloud.Entities)
+	if !ok {
+		return nil, unexpectedType("Entities", value)
+	}
+	return result, nil
+}
+
+// Embeddings records the call and returns the next scripted result.
+func (f *Fake) Embeddings(params nlpcloud.EmbeddingsParams, opts ...nlpcloud.Option) (*nlpcloud.Embeddings, error) {
+	value, err := f.call("Embeddings", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Embeddings{}, nil
+	}
+	result, ok := value.(*nlpcloud.Embeddings)
+	if !ok {
+		return nil, unexpectedType("Embeddings", value)
+	}
+	return result, nil
+}
+
+// Generation records the call and returns the next scripted result.
+func (f *Fake) Generation(params nlpcloud.GenerationParams, opts ...nlpcloud.Option) (*nlpcloud.Generation, error) {
+	value, err := f.call("Generation", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Generation{}, nil
+	}
+	result, ok := value.(*nlpcloud.Generation)
+	if !ok {
+		return nil, unexpectedType("Generation", value)
+	}
+	return result, nil
+}
+
+// StreamingGeneration records the call and returns the next scripted result.
+func (f *Fake) StreamingGeneration(params nlpcloud.GenerationParams, opts ...nlpcloud.Option) (io.ReadCloser, error) {
+	value, err := f.call("StreamingGeneration", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	result, ok := value.(io.ReadCloser)
+	if !ok {
+		return nil, unexpectedType("StreamingGeneration", value)
+	}
+	return result, nil
+}
+
+// BatchGeneration records the call and returns the next scripted result.
+func (f *Fake) BatchGeneration(params nlpcloud.BatchGenerationParams, opts ...nlpcloud.Option) (*nlpcloud.BatchGeneration, error) {
+	value, err := f.call("BatchGeneration", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.BatchGeneration{}, nil
+	}
+	result, ok := value.(*nlpcloud.BatchGeneration)
+	if !ok {
+		return nil, unexpectedType("BatchGeneration", value)
+	}
+	return result, nil
+}
+
+// GSCorrection records the call and returns the next scripted result.
+func (f *Fake) GSCorrection(params nlpcloud.GSCorrectionParams, opts ...nlpcloud.Option) (*nlpcloud.GSCorrection, error) {
+	value, err := f.call("GSCorrection", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.GSCorrection{}, nil
+	}
+	result, ok := value.(*nlpcloud.GSCorrection)
+	if !ok {
+		return nil, unexpectedType("GSCorrection", value)
+	}
+	return result, nil
+}
+
+// ImageGeneration records the call and returns the next scripted result.
+func (f *Fake) ImageGeneration(params nlpcloud.ImageGenerationParams, opts ...nlpcloud.Option) (*nlpcloud.ImageGeneration, error) {
+	value, err := f.call("ImageGeneration", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.ImageGeneration{}, nil
+	}
+	result, ok := value.(*nlpcloud.ImageGeneration)
+	if !ok {
+		return nil, unexpectedType("ImageGeneration", value)
+	}
+	return result, nil
+}
+
+// IntentClassification records the call and returns the next scripted result.
+func (f *Fake) IntentClassification(params nlpcloud.IntentClassificationParams, opts ...nlpcloud.Option) (*nlpcloud.IntentClassification, error) {
+	value, err := f.call("IntentClassification", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.IntentClassification{}, nil
+	}
+	result, ok := value.(*nlpcloud.IntentClassification)
+	if !ok {
+		return nil, unexpectedType("IntentClassification", value)
+	}
+	return result, nil
+}
+
+// KwKpExtraction records the call and returns the next scripted result.
+func (f *Fake) KwKpExtraction(params nlpcloud.KwKpExtractionParams, opts ...nlpcloud.Option) (*nlpcloud.KwKpExtraction, error) {
+	value, err := f.call("KwKpExtraction", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.KwKpExtraction{}, nil
+	}
+	result, ok := value.(*nlpcloud.KwKpExtraction)
+	if !ok {
+		return nil, unexpectedType("KwKpExtraction", value)
+	}
+	return result, nil
+}
+
+// LangDetection records the call and returns the next scripted result.
+func (f *Fake) LangDetection(params nlpcloud.LangDetectionParams, opts ...nlpcloud.Option) (*nlpcloud.LangDetection, error) {
+	value, err := f.call("LangDetection", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.LangDetection{}, nil
+	}
+	result, ok := value.(*nlpcloud.LangDetection)
+	if !ok {
+		return nil, unexpectedType("LangDetection", value)
+	}
+	return result, nil
+}
+
+// Paraphrasing records the call and returns the next scripted result.
+func (f *Fake) Paraphrasing(params nlpcloud.ParaphrasingParams, opts ...nlpcloud.Option) (*nlpcloud.Paraphrasing, error) {
+	value, err := f.call("Paraphrasing", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Paraphrasing{}, nil
+	}
+	result, ok := value.(*nlpcloud.Paraphrasing)
+	if !ok {
+		return nil, unexpectedType("Paraphrasing", value)
+	}
+	return result, nil
+}
+
+// Question records the call and returns the next scripted result.
+func (f *Fake) Question(params nlpcloud.QuestionParams, opts ...nlpcloud.Option) (*nlpcloud.Question, error) {
+	value, err := f.call("Question", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Question{}, nil
+	}
+	result, ok := value.(*nlpcloud.Question)
+	if !ok {
+		return nil, unexpectedType("Question", value)
+	}
+	return result, nil
+}
+
+// SemanticSearch records the call and returns the next scripted result.
+func (f *Fake) SemanticSearch(params nlpcloud.SemanticSearchParams, opts ...nlpcloud.Option) (*nlpcloud.SemanticSearch, error) {
+	value, err := f.call("SemanticSearch", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.SemanticSearch{}, nil
+	}
+	result, ok := value.(*nlpcloud.SemanticSearch)
+	if !ok {
+		return nil, unexpectedType("SemanticSearch", value)
+	}
+	return result, nil
+}
+
+// SemanticSimilarity records the call and returns the next scripted result.
+func (f *Fake) SemanticSimilarity(params nlpcloud.SemanticSimilarityParams, opts ...nlpcloud.Option) (*nlpcloud.SemanticSimilarity, error) {
+	value, err := f.call("SemanticSimilarity", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.SemanticSimilarity{}, nil
+	}
+	result, ok := value.(*nlpcloud.SemanticSimilarity)
+	if !ok {
+		return nil, unexpectedType("SemanticSimilarity", value)
+	}
+	return result, nil
+}
+
+// SentenceDependencies records the call and returns the next scripted result.
+func (f *Fake) SentenceDependencies(params nlpcloud.SentenceDependenciesParams, opts ...nlpcloud.Option) (*nlpcloud.SentenceDependencies, error) {
+	value, err := f.call("SentenceDependencies", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.SentenceDependencies{}, nil
+	}
+	result, ok := value.(*nlpcloud.SentenceDependencies)
+	if !ok {
+		return nil, unexpectedType("SentenceDependencies", value)
+	}
+	return result, nil
+}
+
+// Sentiment records the call and returns the next scripted result.
+func (f *Fake) Sentiment(params nlpcloud.SentimentParams, opts ...nlpcloud.Option) (*nlpcloud.Sentiment, error) {
+	value, err := f.call("Sentiment", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Sentiment{}, nil
+	}
+	result, ok := value.(*nlpcloud.Sentiment)
+	if !ok {
+		return nil, unexpectedType("Sentiment", value)
+	}
+	return result, nil
+}
+
+// SpeechSynthesis records the call and returns the next scripted result.
+func (f *Fake) SpeechSynthesis(params nlpcloud.SpeechSynthesisParams, opts ...nlpcloud.Option) (*nlpcloud.SpeechSynthesis, error) {
+	value, err := f.call("SpeechSynthesis", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.SpeechSynthesis{}, nil
+	}
+	result, ok := value.(*nlpcloud.SpeechSynthesis)
+	if !ok {
+		return nil, unexpectedType("SpeechSynthesis", value)
+	}
+	return result, nil
+}
+
+// Summarization records the call and returns the next scripted result.
+func (f *Fake) Summarization(params nlpcloud.SummarizationParams, opts ...nlpcloud.Option) (*nlpcloud.Summarization, error) {
+	value, err := f.call("Summarization", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Summarization{}, nil
+	}
+	result, ok := value.(*nlpcloud.Summarization)
+	if !ok {
+		return nil, unexpectedType("Summarization", value)
+	}
+	return result, nil
+}
+
+// BatchSummarization records the call and returns the next scripted result.
+func (f *Fake) BatchSummarization(params nlpcloud.BatchSummarizationParams, opts ...nlpcloud.Option) (*nlpcloud.BatchSummarization, error) {
+	value, err := f.call("BatchSummarization", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.BatchSummarization{}, nil
+	}
+	result, ok := value.(*nlpcloud.BatchSummarization)
+	if !ok {
+		return nil, unexpectedType("BatchSummarization", value)
+	}
+	return result, nil
+}
+
+// Tokens records the call and returns the next scripted result.
+func (f *Fake) Tokens(params nlpcloud.TokensParams, opts ...nlpcloud.Option) (*nlpcloud.Tokens, error) {
+	value, err := f.call("Tokens", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Tokens{}, nil
+	}
+	result, ok := value.(*nlpcloud.Tokens)
+	if !ok {
+		return nil, unexpectedType("Tokens", value)
+	}
+	return result, nil
+}
+
+// Translation records the call and returns the next scripted result.
+func (f *Fake) Translation(params nlpcloud.TranslationParams, opts ...nlpcloud.Option) (*nlpcloud.Translation, error) {
+	value, err := f.call("Translation", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.Translation{}, nil
+	}
+	result, ok := value.(*nlpcloud.Translation)
+	if !ok {
+		return nil, unexpectedType("Translation", value)
+	}
+	return result, nil
+}
+
+// BatchTranslation records the call and returns the next scripted result.
+func (f *Fake) BatchTranslation(params nlpcloud.BatchTranslationParams, opts ...nlpcloud.Option) (*nlpcloud.BatchTranslation, error) {
+	value, err := f.call("BatchTranslation", params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &nlpcloud.BatchTranslation{}, nil
+	}
+	result, ok := value.(*nlpcloud.BatchTranslation)
+	if !ok {
+		return nil, unexpectedType("BatchTranslation", value)
+	}
+	return result, nil
+}
+
+// Makes sure the *Fake works with every capability.
+var (
+	_ nlpcloud.AdGenerator        = (*Fake)(nil)
+	_ nlpcloud.Transcriber        = (*Fake)(nil)
+	_ nlpcloud.AsyncResultGetter  = (*Fake)(nil)
+	_ nlpcloud.Chatter            = (*Fake)(nil)
+	_ nlpcloud.StreamingChatter   = (*Fake)(nil)
+	_ nlpcloud.Classifier         = (*Fake)(nil)
+	_ nlpcloud.BatchClassifier    = (*Fake)(nil)
+	_ nlpcloud.CodeGenerator      = (*Fake)(nil)
+	_ nlpcloud.DependencyParser   = (*Fake)(nil)
+	_ nlpcloud.EntityExtractor    = (*Fake)(nil)
+	_ nlpcloud.Embedder           = (*Fake)(nil)
+	_ nlpcloud.Generator          = (*Fake)(nil)
+	_ nlpcloud.StreamingGenerator = (*Fake)(nil)
+	_ nlpcloud.BatchGenerator     = (*Fake)(nil)
+	_ nlpcloud.GrammarCorrector   = (*Fake)(nil)
+	_ nlpcloud.ImageGenerator     = (*Fake)(nil)
+	_ nlpcloud.IntentClassifier   = (*Fake)(nil)
+	_ nlpcloud.KeywordExtractor   = (*Fake)(nil)
+	_ nlpcloud.LanguageDetector   = (*Fake)(nil)
+	_ nlpcloud.Paraphraser        = (*Fake)(nil)
+	_ nlpcloud.QuestionAnswerer   = (*Fake)(nil)
+	_ nlpcloud.SemanticSearcher   = (*Fake)(nil)
+	_ nlpcloud.SimilarityScorer   = (*Fake)(nil)
+	_ nlpcloud.SentimentAnalyzer  = (*Fake)(nil)
+	_ nlpcloud.SpeechSynthesizer  = (*Fake)(nil)
+	_ nlpcloud.Summarizer         = (*Fake)(nil)
+	_ nlpcloud.BatchSummarizer    = (*Fake)(nil)
+	_ nlpcloud.Tokenizer          = (*Fake)(nil)
+	_ nlpcloud.Translator         = (*Fake)(nil)
+	_ nlpcloud.BatchTranslator    = (*Fake)(nil)
+)
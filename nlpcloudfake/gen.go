@@ -0,0 +1,139 @@
+//go:build ignore
+// +build ignore
+
+// gen generates the Fake methods out of the Client methods in api.go, and
+// the assertions out of the capability interfaces in interfaces.go.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"strings"
+)
+
+const header = `// Code generated by gen.go; DO NOT EDIT.
+
+package nlpcloudfake
+
+import (
+	"io"
+	"strings"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+`
+
+const pointerMethod = `
+// %[1]s records the call and returns the next scripted result.
+func (f *Fake) %[1]s(params %[2]s, opts ...nlpcloud.Option) (%[3]s, error) {
+	value, err := f.call(%[1]q, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return &%[4]s{}, nil
+	}
+	result, ok := value.(%[3]s)
+	if !ok {
+		return nil, unexpectedType(%[1]q, value)
+	}
+	return result, nil
+}
+`
+
+const streamMethod = `
+// %[1]s records the call and returns the next scripted result.
+func (f *Fake) %[1]s(params %[2]s, opts ...nlpcloud.Option) (io.ReadCloser, error) {
+	value, err := f.call(%[1]q, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+	result, ok := value.(io.ReadCloser)
+	if !ok {
+		return nil, unexpectedType(%[1]q, value)
+	}
+	return result, nil
+}
+`
+
+func main() {
+	fset := token.NewFileSet()
+	var buf bytes.Buffer
+	buf.WriteString(header)
+
+	api, err := parser.ParseFile(fset, "../api.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, decl := range api.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Recv == nil || !fn.Name.IsExported() || typeString(fn.Recv.List[0].Type) != "*nlpcloud.Client" {
+			continue
+		}
+		params := fn.Type.Params.List
+		results := fn.Type.Results.List
+		if len(params) != 2 || len(results) != 2 {
+			log.Fatalf("unexpected signature for %v", fn.Name.Name)
+		}
+		paramsType := typeString(params[0].Type)
+		resultType := typeString(results[0].Type)
+		if resultType == "io.ReadCloser" {
+			fmt.Fprintf(&buf, streamMethod, fn.Name.Name, paramsType)
+		} else {
+			fmt.Fprintf(&buf, pointerMethod, fn.Name.Name, paramsType, resultType, strings.TrimPrefix(resultType, "*"))
+		}
+	}
+
+	interfaces, err := parser.ParseFile(fset, "../interfaces.go", nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+	buf.WriteString("\n// Makes sure the *Fake works with every capability.\nvar (\n")
+	for _, decl := range interfaces.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if _, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+				fmt.Fprintf(&buf, "\t_ nlpcloud.%s = (*Fake)(nil)\n", typeSpec.Name.Name)
+			}
+		}
+	}
+	buf.WriteString(")\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile("fake_gen.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// typeString formats a type of the nlpcloud package, qualifying its
+// identifiers.
+func typeString(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return "nlpcloud." + expr.Name
+	case *ast.StarExpr:
+		return "*" + typeString(expr.X)
+	case *ast.Ellipsis:
+		return "..." + typeString(expr.Elt)
+	case *ast.SelectorExpr:
+		return expr.X.(*ast.Ident).Name + "." + expr.Sel.Name
+	}
+	log.Fatalf("unsupported type %T", expr)
+	return ""
+}
@@ -0,0 +1,217 @@
+package nlpcloudtest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// endpoint describes an API endpoint served by the Server.
+type endpoint struct {
+	// required lists the JSON fields that must be set.
+	required []string
+	// anyOf lists JSON fields out of which at least one must be set.
+	anyOf []string
+	// streaming is true if the endpoint supports token streaming.
+	streaming bool
+	// response builds the default response out of the request parameters.
+	response func(params map[string]interface{}) interface{}
+	// text extracts the streamed text out of the default response.
+	text func(response interface{}) string
+}
+
+// endpoints holds every endpoint of the API, by name.
+var endpoints = map[string]endpoint{
+	"ad-generation": {
+		required: []string{"keywords"},
+		response: constant(nlpcloud.AdGeneration{GeneratedText: "generated ad"}),
+	},
+	"asr": {
+		anyOf: []string{"url", "encoded_file"},
+		response: constant(nlpcloud.ASR{
+			Text:     "transcribed text",
+			Duration: 1,
+			Language: "en",
+			Segments: []nlpcloud.Segment{{ID: 0, Starter: 0, End: 1, Text: "transcribed text"}},
+		}),
+	},
+	"batch-classification": {
+		required: []string{"texts", "labels"},
+		response: func(params map[string]interface{}) interface{} {
+			return nlpcloud.BatchClassification{Scores: make([]float64, count(params, "texts"))}
+		},
+	},
+	"batch-generation": {
+		required: []string{"texts"},
+		response: func(params map[string]interface{}) interface{} {
+			generations := make([]nlpcloud.Generation, count(params, "texts"))
+			for i := range generations {
+				generations[i] = nlpcloud.Generation{GeneratedText: "generated text", NbGeneratedTokens: 2, NbInputTokens: 1}
+			}
+			return nlpcloud.BatchGeneration{Generations: generations}
+		},
+	},
+	"batch-summarization": {
+		required: []string{"texts"},
+		response: func(params map[string]interface{}) interface{} {
+			return nlpcloud.BatchSummarization{SummaryTexts: repeat("summary", count(params, "texts"))}
+		},
+	},
+	"batch-translation": {
+		required: []string{"texts"},
+		response: func(params map[string]interface{}) interface{} {
+			return nlpcloud.BatchTranslation{TranslationTexts: repeat("translation", count(params, "texts"))}
+		},
+	},
+	"chatbot": {
+		required:  []string{"input"},
+		streaming: true,
+		response: func(params map[string]interface{}) interface{} {
+			input, _ := params["input"].(string)
+			return nlpcloud.Chatbot{
+				Response: "chatbot response",
+				History:  []nlpcloud.Exchange{{Input: input, Response: "chatbot response"}},
+			}
+		},
+		text: func(response interface{}) string {
+			return response.(nlpcloud.Chatbot).Response
+		},
+	},
+	"classification": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Classification{Labels: []string{"label"}, Scores: []float64{1}}),
+	},
+	"code-generation": {
+		required: []string{"instruction"},
+		response: constant(nlpcloud.CodeGeneration{GeneratedCode: "generated code"}),
+	},
+	"dependencies": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Dependencies{Words: []nlpcloud.Word{{Text: "word", Tag: "NN"}}, Arcs: []nlpcloud.Arc{}}),
+	},
+	"embeddings": {
+		required: []string{"sentences"},
+		response: func(params map[string]interface{}) interface{} {
+			embeddings := make([][]float64, count(params, "sentences"))
+			for i := range embeddings {
+				embeddings[i] = []float64{1, 0, 0}
+			}
+			return nlpcloud.Embeddings{Embeddings: embeddings}
+		},
+	},
+	"entities": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Entities{Entities: []nlpcloud.Entity{}}),
+	},
+	"generation": {
+		required:  []string{"text"},
+		streaming: true,
+		response:  constant(nlpcloud.Generation{GeneratedText: "generated text", NbGeneratedTokens: 2, NbInputTokens: 1}),
+		text: func(response interface{}) string {
+			return response.(nlpcloud.Generation).GeneratedText
+		},
+	},
+	"gs-correction": {
+		required: []string{"text"},
+		response: constant(nlpcloud.GSCorrection{Correction: "corrected text"}),
+	},
+	"image-generation": {
+		required: []string{"text"},
+		response: constant(nlpcloud.ImageGeneration{URL: "https://example.com/image.png"}),
+	},
+	"intent-classification": {
+		required: []string{"text"},
+		response: constant(nlpcloud.IntentClassification{Intent: "intent"}),
+	},
+	"kw-kp-extraction": {
+		required: []string{"text"},
+		response: constant(nlpcloud.KwKpExtraction{KeywordsAndKeyphrases: []string{"keyword"}}),
+	},
+	"langdetection": {
+		required: []string{"text"},
+		response: constant(nlpcloud.LangDetection{Languages: []map[string]float64{{"en": 1}}}),
+	},
+	"paraphrasing": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Paraphrasing{ParaphrasedText: "paraphrased text"}),
+	},
+	"question": {
+		required: []string{"question"},
+		response: constant(nlpcloud.Question{Answer: "answer", Score: 1, Start: 0, End: 6}),
+	},
+	"semantic-search": {
+		required: []string{"text"},
+		response: constant(nlpcloud.SemanticSearch{SearchResults: []nlpcloud.SearchResult{{Score: 1, Text: "matching text"}}}),
+	},
+	"semantic-similarity": {
+		required: []string{"sentences"},
+		response: constant(nlpcloud.SemanticSimilarity{Score: 1}),
+	},
+	"sentence-dependencies": {
+		required: []string{"text"},
+		response: constant(nlpcloud.SentenceDependencies{SentenceDependencies: []nlpcloud.SentenceDependency{}}),
+	},
+	"sentiment": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Sentiment{ScoredLabels: []nlpcloud.ScoredLabel{{Label: "POSITIVE", Score: 1}}}),
+	},
+	"speech-synthesis": {
+		required: []string{"text"},
+		response: constant(nlpcloud.SpeechSynthesis{URL: "https://example.com/audio.wav"}),
+	},
+	"summarization": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Summarization{SummaryText: "summary"}),
+	},
+	"tokens": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Tokens{Tokens: []nlpcloud.Token{}}),
+	},
+	"translation": {
+		required: []string{"text"},
+		response: constant(nlpcloud.Translation{TranslationText: "translation"}),
+	},
+}
+
+// Endpoints returns the names of the endpoints served by the Server.
+func Endpoints() []string {
+	names := make([]string, 0, len(endpoints))
+	for name := range endpoints {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func constant(response interface{}) func(map[string]interface{}) interface{} {
+	return func(map[string]interface{}) interface{} {
+		return response
+	}
+}
+
+// count returns the length of the list field of params.
+func count(params map[string]interface{}, field string) int {
+	list, _ := params[field].([]interface{})
+	return len(list)
+}
+
+func repeat(s string, n int) []string {
+	list := make([]string, n)
+	for i := range list {
+		list[i] = s
+	}
+	return list
+}
+
+// words splits text into chunks of one word each, keeping the spaces.
+func words(text string) []string {
+	var chunks []string
+	for i, word := range strings.Split(text, " ") {
+		if i > 0 {
+			word = " " + word
+		}
+		chunks = append(chunks, word)
+	}
+	return chunks
+}
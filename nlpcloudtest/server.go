@@ -0,0 +1,518 @@
+// Package nlpcloudtest provides a fake NLP Cloud API server for testing code
+// built on the nlpcloud Client.
+//
+//	server := nlpcloudtest.NewServer("<token>")
+//	defer server.Close()
+//	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn"})
+//
+//	server.Enqueue("summarization", nlpcloudtest.RateLimited(time.Second))
+//	summarization, err := client.Summarization(nlpcloud.SummarizationParams{Text: "..."})
+//
+// The server understands every endpoint of the API, checks the
+// authentication header and the required JSON fields, and returns canned
+// responses unless programmed otherwise. Asynchronous and streaming
+// requests are simulated, and received requests are recorded.
+package nlpcloudtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// asyncResultPath is the path of the async results, under "/v1/".
+const asyncResultPath = "get-async-result/"
+
+// RecordedRequest holds a request received by the Server.
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   []byte
+	// Params holds the decoded JSON body.
+	Params map[string]interface{}
+
+	// Endpoint is the endpoint name (e.g. "summarization"), or
+	// "async-result" for async result polls.
+	Endpoint  string
+	Model     string
+	GPU       bool
+	Async     bool
+	Lang      string
+	Streaming bool
+}
+
+// Decode unmarshals the request body into dst (e.g. *nlpcloud.SummarizationParams).
+func (r RecordedRequest) Decode(dst interface{}) error {
+	return json.Unmarshal(r.Body, dst)
+}
+
+// Response is a response programmed on the Server.
+type Response struct {
+	// Status is the HTTP status. Default is 200.
+	Status int
+	Header http.Header
+	// Body is sent as is if it is a string or a []byte, and marshaled to
+	// JSON otherwise.
+	Body interface{}
+	// Chunks are streamed instead of the Body, followed by the end of
+	// stream marker.
+	Chunks []string
+	// Delay is waited before responding.
+	Delay time.Duration
+}
+
+// JSON returns a Response with the given status and JSON body.
+func JSON(status int, body interface{}) Response {
+	return Response{Status: status, Body: body}
+}
+
+// Error returns a Response failing with the given status and detail.
+func Error(status int, detail string) Response {
+	return Response{Status: status, Body: map[string]interface{}{"detail": detail}}
+}
+
+// RateLimited returns a 429 Response with a Retry-After header.
+func RateLimited(retryAfter time.Duration) Response {
+	resp := Error(http.StatusTooManyRequests, "Too many requests.")
+	resp.Header = http.Header{"Retry-After": {strconv.Itoa(int(retryAfter.Seconds()))}}
+	return resp
+}
+
+// ServerError returns a Response failing with the given 5xx status.
+func ServerError(status int) Response {
+	return Error(status, http.StatusText(status))
+}
+
+// Stream returns a Response streaming the given chunks.
+func Stream(chunks ...string) Response {
+	return Response{Status: http.StatusOK, Chunks: chunks}
+}
+
+// Responder builds the Response to a request.
+type Responder func(req RecordedRequest) Response
+
+// Server is a fake NLP Cloud API server.
+type Server struct {
+	// URL is the base URL of the server.
+	URL string
+	// Token is the expected API token. If empty, any token is accepted.
+	Token string
+
+	server *httptest.Server
+
+	mu                sync.Mutex
+	requests          []RecordedRequest
+	queues            map[string][]Response
+	responders        map[string]Responder
+	asyncPendingPolls int
+	jobs              map[string]*asyncJob
+	nextJob           int
+}
+
+// asyncJob holds the state of a simulated async request.
+type asyncJob struct {
+	createdOn   time.Time
+	requestBody string
+	response    Response
+	pending     int
+}
+
+// NewServer starts a new Server expecting the given token.
+// It must be closed with Close.
+func NewServer(token string) *Server {
+	s := &Server{
+		Token:      token,
+		queues:     map[string][]Response{},
+		responders: map[string]Responder{},
+		jobs:       map[string]*asyncJob{},
+	}
+	s.server = httptest.NewServer(s)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts down the server.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// HTTPClient returns an HTTP client sending all the requests to the server,
+// whatever their host.
+func (s *Server) HTTPClient() *http.Client {
+	target, _ := url.Parse(s.server.URL)
+	return &http.Client{
+		Transport: &rewriteTransport{
+			target: target,
+			next:   s.server.Client().Transport,
+		},
+	}
+}
+
+// NewClient returns a Client connected to the server. The server token is
+// used if the params have none.
+func (s *Server) NewClient(params nlpcloud.ClientParams) *nlpcloud.Client {
+	if params.Token == "" {
+		params.Token = s.Token
+	}
+	return nlpcloud.NewClient(s.HTTPClient(), params)
+}
+
+// Enqueue programs responses to be returned once each, in order, by the
+// endpoint. Use "async-result" for async result polls.
+func (s *Server) Enqueue(endpoint string, responses ...Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queues[endpoint] = append(s.queues[endpoint], responses...)
+}
+
+// Handle programs the responder of the endpoint, used once the enqueued
+// responses are consumed. A nil responder restores the canned responses.
+func (s *Server) Handle(endpoint string, responder Responder) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if responder == nil {
+		delete(s.responders, endpoint)
+		return
+	}
+	s.responders[endpoint] = responder
+}
+
+// SetAsyncPendingPolls sets the number of polls answered as pending before
+// an async result is available. Default is 0.
+func (s *Server) SetAsyncPendingPolls(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.asyncPendingPolls = n
+}
+
+// Requests returns the requests received so far.
+func (s *Server) Requests() []RecordedRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RecordedRequest(nil), s.requests...)
+}
+
+// RequestsFor returns the requests received so far by the endpoint.
+func (s *Server) RequestsFor(endpoint string) []RecordedRequest {
+	var requests []RecordedRequest
+	for _, req := range s.Requests() {
+		if req.Endpoint == endpoint {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+// LastRequest returns the last request received, if any.
+func (s *Server) LastRequest() (RecordedRequest, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return RecordedRequest{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// Reset forgets the received requests, the programmed responses and the
+// async jobs.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+	s.queues = map[string][]Response{}
+	s.responders = map[string]Responder{}
+	s.jobs = map[string]*asyncJob{}
+}
+
+// ServeHTTP serves the fake API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeResponse(w, r, Error(http.StatusBadRequest, err.Error()))
+		return
+	}
+	req := parseRequest(r, body)
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	if resp, ok := s.check(r, req); !ok {
+		writeResponse(w, r, resp)
+		return
+	}
+
+	if req.Endpoint == "async-result" {
+		writeResponse(w, r, s.poll(strings.TrimPrefix(req.Path, "/v1/"+asyncResultPath)))
+		return
+	}
+
+	resp := s.respond(req)
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if req.Async && status == http.StatusOK {
+		writeResponse(w, r, s.submit(req, resp))
+		return
+	}
+	writeResponse(w, r, resp)
+}
+
+// check validates the method, the authentication and the parameters of
+// the request.
+func (s *Server) check(r *http.Request, req RecordedRequest) (Response, bool) {
+	method := http.MethodPost
+	if req.Endpoint == "async-result" {
+		method = http.MethodGet
+	}
+	if r.Method != method {
+		return Error(http.StatusMethodNotAllowed, "Method Not Allowed"), false
+	}
+
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Token ") || (s.Token != "" && auth != "Token "+s.Token) {
+		return Error(http.StatusUnauthorized, "Invalid token."), false
+	}
+
+	if req.Endpoint == "async-result" {
+		return Response{}, true
+	}
+	ep, ok := endpoints[req.Endpoint]
+	if !ok {
+		return Error(http.StatusNotFound, "Not Found"), false
+	}
+	if req.Params == nil {
+		return Error(http.StatusBadRequest, "Invalid JSON body."), false
+	}
+	var missing []interface{}
+	for _, field := range ep.required {
+		if req.Params[field] == nil {
+			missing = append(missing, validationError(field))
+		}
+	}
+	if len(ep.anyOf) > 0 {
+		found := false
+		for _, field := range ep.anyOf {
+			found = found || req.Params[field] != nil
+		}
+		if !found {
+			missing = append(missing, validationError(strings.Join(ep.anyOf, "|")))
+		}
+	}
+	if len(missing) > 0 {
+		return JSON(http.StatusUnprocessableEntity, map[string]interface{}{"detail": missing}), false
+	}
+	if req.Streaming && !ep.streaming {
+		return Error(http.StatusBadRequest, "Streaming is not supported by this endpoint."), false
+	}
+	return Response{}, true
+}
+
+func validationError(field string) map[string]interface{} {
+	return map[string]interface{}{
+		"loc":  []string{"body", field},
+		"msg":  "field required",
+		"type": "value_error.missing",
+	}
+}
+
+// respond returns the programmed or canned response to the request.
+func (s *Server) respond(req RecordedRequest) Response {
+	s.mu.Lock()
+	if queue := s.queues[req.Endpoint]; len(queue) > 0 {
+		s.queues[req.Endpoint] = queue[1:]
+		s.mu.Unlock()
+		return queue[0]
+	}
+	responder := s.responders[req.Endpoint]
+	s.mu.Unlock()
+	if responder != nil {
+		return responder(req)
+	}
+
+	ep := endpoints[req.Endpoint]
+	resp := Response{Status: http.StatusOK, Body: ep.response(req.Params)}
+	if req.Streaming && ep.text != nil {
+		resp.Chunks = words(ep.text(resp.Body))
+	}
+	return resp
+}
+
+// submit registers an async job and returns the URL of its result.
+func (s *Server) submit(req RecordedRequest, resp Response) Response {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextJob++
+	id := strconv.Itoa(s.nextJob)
+	s.jobs[id] = &asyncJob{
+		createdOn:   time.Now(),
+		requestBody: string(req.Body),
+		response:    resp,
+		pending:     s.asyncPendingPolls,
+	}
+	return JSON(http.StatusAccepted, nlpcloud.Async{URL: "https://api.nlpcloud.io/v1/" + asyncResultPath + id})
+}
+
+// poll returns the state of an async job.
+func (s *Server) poll(id string) Response {
+	s.mu.Lock()
+	if queue := s.queues["async-result"]; len(queue) > 0 {
+		s.queues["async-result"] = queue[1:]
+		s.mu.Unlock()
+		return queue[0]
+	}
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return Error(http.StatusNotFound, "Not Found")
+	}
+	if job.pending > 0 {
+		job.pending--
+		return Response{Status: http.StatusAccepted, Body: ""}
+	}
+
+	status := job.response.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	content, _ := encodeBody(job.response.Body)
+	result := nlpcloud.AsyncResult{
+		CreatedOn:   job.createdOn,
+		FinishedOn:  time.Now(),
+		RequestBody: job.requestBody,
+		HTTPCode:    status,
+		Content:     string(content),
+	}
+	if status != http.StatusOK {
+		result.ErrorDetail = string(content)
+	}
+	return JSON(http.StatusOK, result)
+}
+
+// parseRequest records a request, parsing its path:
+// "/v1/[gpu/][async/][<lang>/]<model>/<endpoint>".
+func parseRequest(r *http.Request, body []byte) RecordedRequest {
+	req := RecordedRequest{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Header: r.Header.Clone(),
+		Body:   body,
+	}
+	if len(body) > 0 {
+		var params map[string]interface{}
+		if err := json.Unmarshal(body, &params); err == nil {
+			req.Params = params
+			req.Streaming, _ = params["stream"].(bool)
+		}
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1/")
+	if strings.HasPrefix(path, asyncResultPath) {
+		req.Endpoint = "async-result"
+		return req
+	}
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(segments) > 0 && segments[0] == "gpu" {
+		req.GPU = true
+		segments = segments[1:]
+	}
+	if len(segments) > 0 && segments[0] == "async" {
+		req.Async = true
+		segments = segments[1:]
+	}
+	if len(segments) == 0 {
+		return req
+	}
+	req.Endpoint = segments[len(segments)-1]
+	segments = segments[:len(segments)-1]
+	// Custom models are named "custom_model/<id>"
+	if len(segments) > 1 && segments[0] != "custom_model" {
+		req.Lang = segments[0]
+		segments = segments[1:]
+	}
+	req.Model = strings.Join(segments, "/")
+	return req
+}
+
+// encodeBody returns the bytes of a response body.
+func encodeBody(body interface{}) ([]byte, error) {
+	switch body := body.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return body, nil
+	case string:
+		return []byte(body), nil
+	default:
+		return json.Marshal(body)
+	}
+}
+
+// writeResponse writes resp, streaming its chunks if any.
+func writeResponse(w http.ResponseWriter, r *http.Request, resp Response) {
+	if resp.Delay > 0 {
+		select {
+		case <-time.After(resp.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	status := resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	if resp.Chunks != nil {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(status)
+		flusher, _ := w.(http.Flusher)
+		for _, chunk := range resp.Chunks {
+			fmt.Fprintf(w, "%s\x00", chunk)
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		io.WriteString(w, "[DONE]")
+		return
+	}
+
+	body, err := encodeBody(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(body) > 0 && w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// rewriteTransport sends all the requests to the target host.
+type rewriteTransport struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (t *rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	req.Host = t.target.Host
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,311 @@
+package nlpcloudtest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// calls holds a valid call of the Client to each endpoint, by endpoint.
+var calls = map[string]func(c *nlpcloud.Client) error{
+	"ad-generation": func(c *nlpcloud.Client) error {
+		_, err := c.AdGeneration(nlpcloud.AdGenerationParams{Keywords: []string{"shoes"}})
+		return err
+	},
+	"asr": func(c *nlpcloud.Client) error {
+		url := "https://example.com/audio.wav"
+		_, err := c.ASR(nlpcloud.ASRParams{URL: &url})
+		return err
+	},
+	"batch-classification": func(c *nlpcloud.Client) error {
+		_, err := c.BatchClassification(nlpcloud.BatchClassificationParams{Texts: []string{"text"}, Labels: []string{"label"}})
+		return err
+	},
+	"batch-generation": func(c *nlpcloud.Client) error {
+		_, err := c.BatchGeneration(nlpcloud.BatchGenerationParams{Texts: []string{"text"}})
+		return err
+	},
+	"batch-summarization": func(c *nlpcloud.Client) error {
+		_, err := c.BatchSummarization(nlpcloud.BatchSummarizationParams{Texts: []string{"text"}})
+		return err
+	},
+	"batch-translation": func(c *nlpcloud.Client) error {
+		_, err := c.BatchTranslation(nlpcloud.BatchTranslationParams{Texts: []string{"text"}})
+		return err
+	},
+	"chatbot": func(c *nlpcloud.Client) error {
+		_, err := c.Chatbot(nlpcloud.ChatbotParams{Input: "input"})
+		return err
+	},
+	"classification": func(c *nlpcloud.Client) error {
+		_, err := c.Classification(nlpcloud.ClassificationParams{Text: "text"})
+		return err
+	},
+	"code-generation": func(c *nlpcloud.Client) error {
+		_, err := c.CodeGeneration(nlpcloud.CodeGenerationParams{Intruction: "instruction"})
+		return err
+	},
+	"dependencies": func(c *nlpcloud.Client) error {
+		_, err := c.Dependencies(nlpcloud.DependenciesParams{Text: "text"})
+		return err
+	},
+	"embeddings": func(c *nlpcloud.Client) error {
+		_, err := c.Embeddings(nlpcloud.EmbeddingsParams{Sentences: []string{"text"}})
+		return err
+	},
+	"entities": func(c *nlpcloud.Client) error {
+		_, err := c.Entities(nlpcloud.EntitiesParams{Text: "text"})
+		return err
+	},
+	"generation": func(c *nlpcloud.Client) error {
+		_, err := c.Generation(nlpcloud.GenerationParams{Text: "text"})
+		return err
+	},
+	"gs-correction": func(c *nlpcloud.Client) error {
+		_, err := c.GSCorrection(nlpcloud.GSCorrectionParams{Text: "text"})
+		return err
+	},
+	"image-generation": func(c *nlpcloud.Client) error {
+		_, err := c.ImageGeneration(nlpcloud.ImageGenerationParams{Text: "text"})
+		return err
+	},
+	"intent-classification": func(c *nlpcloud.Client) error {
+		_, err := c.IntentClassification(nlpcloud.IntentClassificationParams{Text: "text"})
+		return err
+	},
+	"kw-kp-extraction": func(c *nlpcloud.Client) error {
+		_, err := c.KwKpExtraction(nlpcloud.KwKpExtractionParams{Text: "text"})
+		return err
+	},
+	"langdetection": func(c *nlpcloud.Client) error {
+		_, err := c.LangDetection(nlpcloud.LangDetectionParams{Text: "text"})
+		return err
+	},
+	"paraphrasing": func(c *nlpcloud.Client) error {
+		_, err := c.Paraphrasing(nlpcloud.ParaphrasingParams{Text: "text"})
+		return err
+	},
+	"question": func(c *nlpcloud.Client) error {
+		_, err := c.Question(nlpcloud.QuestionParams{Question: "question"})
+		return err
+	},
+	"semantic-search": func(c *nlpcloud.Client) error {
+		_, err := c.SemanticSearch(nlpcloud.SemanticSearchParams{Text: "text"})
+		return err
+	},
+	"semantic-similarity": func(c *nlpcloud.Client) error {
+		_, err := c.SemanticSimilarity(nlpcloud.SemanticSimilarityParams{Sentences: [2]string{"text", "text"}})
+		return err
+	},
+	"sentence-dependencies": func(c *nlpcloud.Client) error {
+		_, err := c.SentenceDependencies(nlpcloud.SentenceDependenciesParams{Text: "text"})
+		return err
+	},
+	"sentiment": func(c *nlpcloud.Client) error {
+		_, err := c.Sentiment(nlpcloud.SentimentParams{Text: "text"})
+		return err
+	},
+	"speech-synthesis": func(c *nlpcloud.Client) error {
+		_, err := c.SpeechSynthesis(nlpcloud.SpeechSynthesisParams{Text: "text"})
+		return err
+	},
+	"summarization": func(c *nlpcloud.Client) error {
+		_, err := c.Summarization(nlpcloud.SummarizationParams{Text: "text"})
+		return err
+	},
+	"tokens": func(c *nlpcloud.Client) error {
+		_, err := c.Tokens(nlpcloud.TokensParams{Text: "text"})
+		return err
+	},
+	"translation": func(c *nlpcloud.Client) error {
+		_, err := c.Translation(nlpcloud.TranslationParams{Text: "text"})
+		return err
+	},
+}
+
+func TestEndpoints(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn", GPU: true, Lang: "fra_Latn"})
+
+	for _, endpoint := range Endpoints() {
+		call, ok := calls[endpoint]
+		if !ok {
+			t.Errorf("%s: missing from calls", endpoint)
+			continue
+		}
+		if err := call(client); err != nil {
+			t.Errorf("%s: %v", endpoint, err)
+			continue
+		}
+		req, ok := server.LastRequest()
+		if !ok {
+			t.Fatalf("%s: no request received", endpoint)
+		}
+		if req.Method != http.MethodPost || req.Path != "/v1/gpu/fra_Latn/bart-large-cnn/"+endpoint {
+			t.Errorf("%s: got %s %s", endpoint, req.Method, req.Path)
+		}
+		if req.Endpoint != endpoint || req.Model != "bart-large-cnn" || !req.GPU || req.Async || req.Lang != "fra_Latn" {
+			t.Errorf("%s: parsed as %+v", endpoint, req)
+		}
+		if got := req.Header.Get("Authorization"); got != "Token token" {
+			t.Errorf("%s: got Authorization %q", endpoint, got)
+		}
+	}
+}
+
+func TestAuthentication(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn", Token: "wrong"})
+	_, err := client.Summarization(nlpcloud.SummarizationParams{Text: "text"})
+	if !errors.Is(err, nlpcloud.ErrUnauthorized) {
+		t.Errorf("got error %v, want ErrUnauthorized", err)
+	}
+
+	client = server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn"})
+	if _, err = client.Summarization(nlpcloud.SummarizationParams{Text: "text"}); err != nil {
+		t.Errorf("got error %v with the server token", err)
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "whisper"})
+
+	_, err := client.ASR(nlpcloud.ASRParams{})
+	var httpErr *nlpcloud.HTTPError
+	if !errors.As(err, &httpErr) || !errors.Is(err, nlpcloud.ErrUnprocessable) {
+		t.Fatalf("got error %v, want ErrUnprocessable", err)
+	}
+
+	_, err = client.BatchClassification(nlpcloud.BatchClassificationParams{Texts: []string{"text"}})
+	if !errors.Is(err, nlpcloud.ErrUnprocessable) {
+		t.Errorf("missing labels: got error %v, want ErrUnprocessable", err)
+	}
+
+	// The fields with a zero value are sent, and accepted
+	if _, err = client.Summarization(nlpcloud.SummarizationParams{}); err != nil {
+		t.Errorf("empty text: got error %v", err)
+	}
+}
+
+func TestAsync(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+	server.SetAsyncPendingPolls(2)
+	client := server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn"})
+
+	job, err := client.AsyncSummarization(nlpcloud.SummarizationParams{Text: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := server.LastRequest()
+	if req.Path != "/v1/async/bart-large-cnn/summarization" || !req.Async {
+		t.Errorf("submitted to %s", req.Path)
+	}
+
+	job.PollPolicy = nlpcloud.PollPolicy{Interval: time.Millisecond}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	summarization, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summarization.SummaryText != "summary" {
+		t.Errorf("got summary %q", summarization.SummaryText)
+	}
+	polls := server.RequestsFor("async-result")
+	if len(polls) != 3 {
+		t.Errorf("got %d polls, want 3", len(polls))
+	}
+	for _, poll := range polls {
+		if poll.Method != http.MethodGet || poll.Header.Get("Authorization") != "Token token" {
+			t.Errorf("got poll %s with Authorization %q", poll.Method, poll.Header.Get("Authorization"))
+		}
+	}
+
+	// A synchronous call to an asynchronous client returns the result URL
+	client = server.NewClient(nlpcloud.ClientParams{Model: "bart-large-cnn", Async: true})
+	_, err = client.Summarization(nlpcloud.SummarizationParams{Text: "text"})
+	var accepted *nlpcloud.AsyncAcceptedError
+	if !errors.As(err, &accepted) || accepted.URL == "" {
+		t.Errorf("got error %v, want *AsyncAcceptedError", err)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "llama-3"})
+
+	body, err := client.StreamingGeneration(nlpcloud.GenerationParams{Text: "text"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := nlpcloud.NewStream(body).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "generated text" {
+		t.Errorf("got streamed text %q", text)
+	}
+	if req, _ := server.LastRequest(); !req.Streaming {
+		t.Errorf("request is not streaming")
+	}
+
+	server.Enqueue("chatbot", Stream("Hello", " there"))
+	body, err = client.StreamingChatbot(nlpcloud.ChatbotParams{Input: "Hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err = nlpcloud.NewStream(body).Collect(); err != nil || text != "Hello there" {
+		t.Errorf("got streamed text %q, %v", text, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	server := NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert"})
+
+	server.Enqueue("sentiment", RateLimited(2*time.Second))
+	_, err := client.Sentiment(nlpcloud.SentimentParams{Text: "text"})
+	var httpErr *nlpcloud.HTTPError
+	if !errors.As(err, &httpErr) || !errors.Is(err, nlpcloud.ErrRateLimited) {
+		t.Fatalf("got error %v, want ErrRateLimited", err)
+	}
+	if httpErr.RetryAfter != 2*time.Second {
+		t.Errorf("got Retry-After %v, want 2s", httpErr.RetryAfter)
+	}
+
+	for _, status := range []int{http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable} {
+		server.Enqueue("sentiment", ServerError(status))
+		_, err = client.Sentiment(nlpcloud.SentimentParams{Text: "text"})
+		if !errors.As(err, &httpErr) || httpErr.Status != status {
+			t.Errorf("got error %v, want status %d", err, status)
+		}
+	}
+
+	// Retried requests get the canned response once the queue is consumed
+	server.Reset()
+	server.Enqueue("sentiment", RateLimited(0), ServerError(http.StatusServiceUnavailable))
+	policy := nlpcloud.RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}
+	sentiment, err := client.Sentiment(nlpcloud.SentimentParams{Text: "text"}, nlpcloud.WithRetryPolicy(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sentiment.ScoredLabels) != 1 || len(server.RequestsFor("sentiment")) != 3 {
+		t.Errorf("got %+v after %d requests", sentiment, len(server.RequestsFor("sentiment")))
+	}
+}
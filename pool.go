@@ -0,0 +1,359 @@
+package nlpcloud
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Job is an item processed by a Pool.
+type Job struct {
+	// ID identifies the job in the checkpoint. Jobs without ID are not
+	// checkpointed.
+	ID    string
+	Input interface{}
+}
+
+// JobResult holds the outcome of a Job.
+type JobResult struct {
+	Job    Job
+	Output interface{}
+	Err    error
+}
+
+// ProcessFunc processes the input of a job with client. The opts carry the
+// pool context, retry policy and rate limit, and must be passed to the
+// client methods:
+//
+//	func(client *nlpcloud.Client, input interface{}, opts ...nlpcloud.Option) (interface{}, error) {
+//		return client.Entities(nlpcloud.EntitiesParams{Text: input.(string)}, opts...)
+//	}
+type ProcessFunc func(client *Client, input interface{}, opts ...Option) (interface{}, error)
+
+// PoolParams wraps all the parameters for the Pool initialization.
+type PoolParams struct {
+	// Workers is the number of jobs processed concurrently. Default is 4.
+	Workers int
+	// RequestsPerSecond caps the requests issued by the pool.
+	// Zero means no cap.
+	RequestsPerSecond float64
+	// Retry is the retry policy of the requests. Default is
+	// DefaultRetryPolicy, retrying on 429 and 5xx errors.
+	Retry *RetryPolicy
+	// Ordered emits the results in the order of the jobs.
+	Ordered bool
+	// Checkpoint records the completed jobs, which are skipped when the
+	// pool is run again.
+	Checkpoint Checkpoint
+}
+
+// PoolProgress holds the progress counters of a Pool.
+type PoolProgress struct {
+	// Submitted counts the jobs read from the input.
+	Submitted uint64
+	// Skipped counts the jobs already completed according to the checkpoint.
+	Skipped   uint64
+	Succeeded uint64
+	Failed    uint64
+}
+
+// poolCounters holds the pool progress, updated atomically.
+type poolCounters struct {
+	submitted uint64
+	skipped   uint64
+	succeeded uint64
+	failed    uint64
+}
+
+// Pool processes jobs with a Client using a fixed number of workers.
+type Pool struct {
+	client   *Client
+	params   PoolParams
+	limiter  RateLimiter
+	counters *poolCounters
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewPool initializes a new Pool issuing the requests with client.
+func NewPool(client *Client, params PoolParams) *Pool {
+	if params.Workers < 1 {
+		params.Workers = 4
+	}
+	if params.Retry == nil {
+		policy := DefaultRetryPolicy()
+		params.Retry = &policy
+	}
+	pool := &Pool{
+		client:   client,
+		params:   params,
+		counters: &poolCounters{},
+	}
+	if params.RequestsPerSecond > 0 {
+		pool.limiter = NewTokenBucket(params.RequestsPerSecond, 1)
+	}
+	return pool
+}
+
+// Run processes the jobs read from jobs with fn, and returns the channel of
+// their results, closed once all the jobs are processed. The results
+// channel must be drained.
+//
+// When ctx is cancelled, no more jobs are read, and the jobs in progress
+// fail with the context error. A job is checkpointed once its result is
+// received from the results channel, unless it failed.
+func (p *Pool) Run(ctx context.Context, jobs <-chan Job, fn ProcessFunc) <-chan JobResult {
+	type task struct {
+		seq uint64
+		job Job
+	}
+	type done struct {
+		seq    uint64
+		result JobResult
+	}
+	tasks := make(chan task)
+	dones := make(chan done)
+	results := make(chan JobResult)
+
+	// Ordered results are buffered until their predecessors are done, so
+	// the jobs in progress are bounded by a window.
+	var window chan struct{}
+	if p.params.Ordered {
+		window = make(chan struct{}, 4*p.params.Workers)
+	}
+
+	go func() {
+		defer close(tasks)
+		var seq uint64
+		for {
+			var job Job
+			select {
+			case <-ctx.Done():
+				return
+			case j, ok := <-jobs:
+				if !ok {
+					return
+				}
+				job = j
+			}
+			atomic.AddUint64(&p.counters.submitted, 1)
+			if job.ID != "" && p.params.Checkpoint != nil && p.params.Checkpoint.Done(job.ID) {
+				atomic.AddUint64(&p.counters.skipped, 1)
+				continue
+			}
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case tasks <- task{seq: seq, job: job}:
+				seq++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	opts := []Option{WithContext(ctx), WithRetryPolicy(*p.params.Retry)}
+	if p.limiter != nil {
+		opts = append(opts, WithRateLimiter(p.limiter))
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < p.params.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range tasks {
+				output, err := fn(p.client, t.job.Input, opts...)
+				dones <- done{seq: t.seq, result: JobResult{Job: t.job, Output: output, Err: err}}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(dones)
+	}()
+
+	go func() {
+		defer close(results)
+		pending := map[uint64]JobResult{}
+		var next uint64
+		for d := range dones {
+			if window == nil {
+				p.emit(results, d.result)
+				continue
+			}
+			pending[d.seq] = d.result
+			for {
+				result, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				p.emit(results, result)
+				<-window
+			}
+		}
+	}()
+	return results
+}
+
+// RunIterator is like Run, reading the jobs from next until it returns
+// false.
+func (p *Pool) RunIterator(ctx context.Context, next func() (Job, bool), fn ProcessFunc) <-chan JobResult {
+	jobs := make(chan Job)
+	go func() {
+		defer close(jobs)
+		for {
+			job, ok := next()
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return p.Run(ctx, jobs, fn)
+}
+
+// emit sends a result, then checkpoints its job.
+func (p *Pool) emit(results chan<- JobResult, result JobResult) {
+	results <- result
+	if result.Err != nil {
+		atomic.AddUint64(&p.counters.failed, 1)
+		return
+	}
+	atomic.AddUint64(&p.counters.succeeded, 1)
+	if result.Job.ID == "" || p.params.Checkpoint == nil {
+		return
+	}
+	if err := p.params.Checkpoint.MarkDone(result.Job.ID); err != nil {
+		p.mu.Lock()
+		if p.err == nil {
+			p.err = err
+		}
+		p.mu.Unlock()
+	}
+}
+
+// Progress returns the progress counters of the pool.
+func (p *Pool) Progress() PoolProgress {
+	return PoolProgress{
+		Submitted: atomic.LoadUint64(&p.counters.submitted),
+		Skipped:   atomic.LoadUint64(&p.counters.skipped),
+		Succeeded: atomic.LoadUint64(&p.counters.succeeded),
+		Failed:    atomic.LoadUint64(&p.counters.failed),
+	}
+}
+
+// Err returns the first error met while writing the checkpoint, if any.
+func (p *Pool) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Checkpoint records the jobs completed by a Pool. It must be safe for
+// concurrent use.
+type Checkpoint interface {
+	// Done reports whether the job with id is completed.
+	Done(id string) bool
+	// MarkDone records the job with id as completed.
+	MarkDone(id string) error
+}
+
+// FileCheckpoint is a Checkpoint appending the IDs of the completed jobs to
+// a file.
+type FileCheckpoint struct {
+	mu   sync.Mutex
+	file *os.File
+	done map[string]bool
+}
+
+// Makes sure the *FileCheckpoint works with the Checkpoint.
+var _ Checkpoint = (*FileCheckpoint)(nil)
+
+// OpenFileCheckpoint opens the checkpoint stored at path, creating it if
+// needed.
+func OpenFileCheckpoint(path string) (*FileCheckpoint, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &FileCheckpoint{file: file, done: map[string]bool{}}
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && err == io.EOF {
+			// The last line was cut when the job was killed
+			_, err = file.Write([]byte("\n"))
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			break
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		var id string
+		if json.Unmarshal(line, &id) == nil {
+			checkpoint.done[id] = true
+		}
+	}
+	return checkpoint, nil
+}
+
+// Done reports whether the job with id is completed.
+func (f *FileCheckpoint) Done(id string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.done[id]
+}
+
+// MarkDone records the job with id as completed.
+func (f *FileCheckpoint) MarkDone(id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.done[id] {
+		return nil
+	}
+	line, err := json.Marshal(id)
+	if err != nil {
+		return err
+	}
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	f.done[id] = true
+	return nil
+}
+
+// Len returns the number of completed jobs.
+func (f *FileCheckpoint) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.done)
+}
+
+// Close closes the checkpoint file.
+func (f *FileCheckpoint) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,176 @@
+package nlpcloud_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+// jobsOf returns a closed channel of jobs with the given IDs, using the
+// index of each job as its input.
+func jobsOf(ids ...string) <-chan nlpcloud.Job {
+	jobs := make(chan nlpcloud.Job, len(ids))
+	for i, id := range ids {
+		jobs <- nlpcloud.Job{ID: id, Input: i}
+	}
+	close(jobs)
+	return jobs
+}
+
+func TestPoolOrdered(t *testing.T) {
+	const n, workers = 50, 3
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = fmt.Sprint(i)
+	}
+
+	// The first job is slow, so the following ones are buffered until the
+	// window is full
+	var started, startedBeforeFirst int64
+	pool := nlpcloud.NewPool(nil, nlpcloud.PoolParams{Workers: workers, Ordered: true})
+	results := pool.Run(context.Background(), jobsOf(ids...), func(client *nlpcloud.Client, input interface{}, opts ...nlpcloud.Option) (interface{}, error) {
+		atomic.AddInt64(&started, 1)
+		if input.(int) == 0 {
+			time.Sleep(100 * time.Millisecond)
+			atomic.StoreInt64(&startedBeforeFirst, atomic.LoadInt64(&started))
+		}
+		return input, nil
+	})
+
+	next := 0
+	for result := range results {
+		if result.Err != nil {
+			t.Fatal(result.Err)
+		}
+		if result.Output != next || result.Job.ID != ids[next] {
+			t.Fatalf("got result %v of job %s, want %d", result.Output, result.Job.ID, next)
+		}
+		next++
+	}
+	if next != n {
+		t.Errorf("got %d results, want %d", next, n)
+	}
+	if got := atomic.LoadInt64(&startedBeforeFirst); got < workers || got > 4*workers {
+		t.Errorf("got %d jobs started before the first one was done, want between %d and %d", got, workers, 4*workers)
+	}
+	if progress := pool.Progress(); progress != (nlpcloud.PoolProgress{Submitted: n, Succeeded: n}) {
+		t.Errorf("got progress %+v", progress)
+	}
+}
+
+func TestPoolCheckpointResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint")
+	// The last line was cut when the previous run was killed
+	if err := os.WriteFile(path, []byte("\"a\"\n\"b\"\n\"c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := nlpcloud.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpoint.Len() != 2 || !checkpoint.Done("a") || !checkpoint.Done("b") || checkpoint.Done("c") {
+		t.Fatalf("got %d completed jobs", checkpoint.Len())
+	}
+
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert"})
+	server.Enqueue("sentiment", nlpcloudtest.Error(400, "Bad request."))
+
+	pool := nlpcloud.NewPool(client, nlpcloud.PoolParams{Workers: 1, Checkpoint: checkpoint})
+	var processed []string
+	for result := range pool.Run(context.Background(), jobsOf("a", "b", "c", "d", "e"), func(client *nlpcloud.Client, input interface{}, opts ...nlpcloud.Option) (interface{}, error) {
+		return client.Sentiment(nlpcloud.SentimentParams{Text: fmt.Sprint(input)}, opts...)
+	}) {
+		processed = append(processed, result.Job.ID)
+		if (result.Err != nil) != (result.Job.ID == "c") {
+			t.Errorf("job %s: got error %v", result.Job.ID, result.Err)
+		}
+	}
+	if fmt.Sprint(processed) != "[c d e]" {
+		t.Errorf("processed %v, want [c d e]", processed)
+	}
+	if progress := pool.Progress(); progress != (nlpcloud.PoolProgress{Submitted: 5, Skipped: 2, Succeeded: 2, Failed: 1}) {
+		t.Errorf("got progress %+v", progress)
+	}
+	if err = pool.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err = checkpoint.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failed job is run again on resume
+	checkpoint, err = nlpcloud.OpenFileCheckpoint(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpoint.Close()
+	for _, id := range []string{"a", "b", "d", "e"} {
+		if !checkpoint.Done(id) {
+			t.Errorf("job %s is not completed", id)
+		}
+	}
+	if checkpoint.Done("c") || checkpoint.Len() != 4 {
+		t.Errorf("got %d completed jobs, want 4", checkpoint.Len())
+	}
+}
+
+func TestPoolCancel(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "distilbert"})
+	server.Handle("sentiment", func(nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+		return nlpcloudtest.Response{Delay: time.Minute}
+	})
+
+	// The jobs never end
+	jobs := make(chan nlpcloud.Job)
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for {
+			select {
+			case jobs <- nlpcloud.Job{Input: "text"}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	pool := nlpcloud.NewPool(client, nlpcloud.PoolParams{Workers: 2})
+	results := pool.Run(ctx, jobs, func(client *nlpcloud.Client, input interface{}, opts ...nlpcloud.Option) (interface{}, error) {
+		return client.Sentiment(nlpcloud.SentimentParams{Text: input.(string)}, opts...)
+	})
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	timeout := time.After(5 * time.Second)
+	n := 0
+	for {
+		select {
+		case result, ok := <-results:
+			if !ok {
+				if n == 0 || n > 2 {
+					t.Errorf("got %d results, want the jobs in progress", n)
+				}
+				if progress := pool.Progress(); progress.Failed != uint64(n) || progress.Succeeded != 0 {
+					t.Errorf("got progress %+v", progress)
+				}
+				return
+			}
+			n++
+			if !errors.Is(result.Err, context.Canceled) {
+				t.Errorf("got error %v, want context.Canceled", result.Err)
+			}
+		case <-timeout:
+			t.Fatal("the pool did not shut down")
+		}
+	}
+}
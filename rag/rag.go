@@ -0,0 +1,305 @@
+// Package rag answers questions about a corpus of documents with the
+// nlpcloud Client: the documents are split into passages indexed by their
+// embeddings, and the passages closest to a question are sent as the
+// context of Question, or of Generation with a prompt template.
+//
+// NLP Cloud serves embeddings, question answering and generation with
+// different models, so a Pipeline uses a Client per model:
+//
+//	embedder := nlpcloud.NewClient(&http.Client{}, nlpcloud.ClientParams{Model: "paraphrase-multilingual-mpnet-base-v2", Token: token})
+//	answerer := nlpcloud.NewClient(&http.Client{}, nlpcloud.ClientParams{Model: "roberta-base-squad2", Token: token})
+//	pipeline, err := rag.New(embedder, vectorindex.New(vectorindex.Params{}), rag.Params{QuestionAnswerer: answerer})
+//	err = pipeline.Add([]rag.Document{{ID: "handbook", Text: handbook}})
+//	answer, err := pipeline.Ask("How many days off do we get?", nil)
+package rag
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/vectorindex"
+)
+
+// The metadata keys holding the location of a passage in its document.
+const (
+	MetadataDocumentID = "document_id"
+	MetadataStart      = "start"
+	MetadataEnd        = "end"
+)
+
+// Document is a document of the corpus.
+type Document struct {
+	ID   string
+	Text string
+	// Metadata is copied to the passages of the document, so searches can
+	// be filtered by document metadata.
+	Metadata map[string]interface{}
+}
+
+// Params wraps all the parameters for the Pipeline initialization.
+type Params struct {
+	// PassageTokens is the maximum number of tokens of a passage.
+	// Default is 200.
+	PassageTokens int
+	// ContextTokens is the maximum number of tokens of the context, which
+	// must fit in the model limit. Default is 1000.
+	ContextTokens int
+	// TopK is the number of passages retrieved for a question. Default is 5.
+	TopK int
+	// CountTokens counts the tokens of a text. Default is
+	// nlpcloud.EstimateTokens.
+	CountTokens func(text string) int
+	// Chunk defines how the passages are batched when computing their
+	// embeddings.
+	Chunk nlpcloud.ChunkParams
+
+	// QuestionAnswerer answers the questions out of the passages, e.g. a
+	// Client of a question answering model. It is required unless
+	// PromptTemplate is set.
+	QuestionAnswerer nlpcloud.QuestionAnswerer
+
+	// PromptTemplate, if set, answers with Generator instead of
+	// QuestionAnswerer, with a text/template prompt using {{.Question}} and
+	// {{.Context}}.
+	PromptTemplate string
+	// Generator answers the questions with PromptTemplate, e.g. a Client of
+	// a generation model.
+	Generator nlpcloud.Generator
+	// Generation holds the parameters of the generations. Its Text is
+	// replaced by the prompt.
+	Generation nlpcloud.GenerationParams
+}
+
+// Pipeline answers questions about the documents added to its index.
+// A Pipeline is safe for concurrent use.
+type Pipeline struct {
+	embedder *nlpcloud.Client
+	index    *vectorindex.Index
+	params   Params
+	prompt   *template.Template
+}
+
+// New initializes a new Pipeline computing the embeddings with embedder, a
+// Client of an embeddings model, and storing the passages in index. The
+// index may be loaded from disk with the passages of a previous pipeline.
+func New(embedder *nlpcloud.Client, index *vectorindex.Index, params Params) (*Pipeline, error) {
+	if params.PassageTokens < 1 {
+		params.PassageTokens = 200
+	}
+	if params.ContextTokens < 1 {
+		params.ContextTokens = 1000
+	}
+	if params.TopK < 1 {
+		params.TopK = 5
+	}
+	if params.CountTokens == nil {
+		params.CountTokens = nlpcloud.EstimateTokens
+	}
+	switch {
+	case params.PromptTemplate == "" && params.QuestionAnswerer == nil:
+		return nil, errors.New("rag: QuestionAnswerer is nil")
+	case params.PromptTemplate != "" && params.Generator == nil:
+		return nil, errors.New("rag: Generator is nil")
+	}
+	pipeline := &Pipeline{embedder: embedder, index: index, params: params}
+	if params.PromptTemplate != "" {
+		prompt, err := template.New("prompt").Parse(params.PromptTemplate)
+		if err != nil {
+			return nil, err
+		}
+		pipeline.prompt = prompt
+	}
+	return pipeline, nil
+}
+
+// Index returns the index of the passages, e.g. to save it.
+func (p *Pipeline) Index() *vectorindex.Index {
+	return p.index
+}
+
+// Add splits the documents into passages, and adds them to the index with
+// their embeddings. The passages of a document added again are replaced.
+// The passages whose embeddings failed are reported in the returned
+// *nlpcloud.PartialError.
+func (p *Pipeline) Add(documents []Document, opts ...nlpcloud.Option) error {
+	var passages []vectorindex.Document
+	for _, document := range documents {
+		// A shorter version of the document has fewer passages
+		p.index.DeleteMatching(vectorindex.Equals(MetadataDocumentID, document.ID))
+		for i, chunk := range nlpcloud.SplitText(document.Text, p.params.PassageTokens, p.params.CountTokens) {
+			metadata := make(map[string]interface{}, len(document.Metadata)+3)
+			for key, value := range document.Metadata {
+				metadata[key] = value
+			}
+			metadata[MetadataDocumentID] = document.ID
+			metadata[MetadataStart] = chunk.Start
+			metadata[MetadataEnd] = chunk.End
+			passages = append(passages, vectorindex.Document{
+				ID:       fmt.Sprintf("%s#%d", document.ID, i),
+				Text:     chunk.Text,
+				Metadata: metadata,
+			})
+		}
+	}
+	return p.index.Ingest(p.embedder, passages, p.params.Chunk, opts...)
+}
+
+// Source is a passage the context of an answer was assembled from.
+type Source struct {
+	DocumentID string
+	Text       string
+	// Start and End are the byte offsets of the passage in its document.
+	Start    int
+	End      int
+	Score    float64
+	Metadata map[string]interface{}
+}
+
+// Answer holds the answer to a question.
+type Answer struct {
+	Answer string
+	// Score is the score of the Question answer, and 0 for generations.
+	Score float64
+	// Sources holds the passages of the context, from the most relevant.
+	Sources []Source
+	// DocumentID is the document holding the answer, if it was found in
+	// the sources.
+	DocumentID string
+	// Start and End are the byte offsets of the answer in its document,
+	// or -1 if it was not found in the sources.
+	Start int
+	End   int
+}
+
+// Ask retrieves the passages closest to question and selected by filter,
+// which may be nil, and answers the question out of them.
+func (p *Pipeline) Ask(question string, filter vectorindex.Filter, opts ...nlpcloud.Option) (*Answer, error) {
+	results, err := p.index.SearchText(p.embedder, question, p.params.TopK, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Assemble the context from the most relevant passages that fit
+	var sources []Source
+	var parts []string
+	// offsets holds the character offset of each source in the context,
+	// as the API counts characters.
+	var offsets []int
+	tokens, chars := 0, 0
+	for _, result := range results {
+		n := p.params.CountTokens(result.Text)
+		if tokens+n > p.params.ContextTokens {
+			continue
+		}
+		tokens += n
+		offsets = append(offsets, chars)
+		chars += utf8.RuneCountInString(result.Text) + 2
+		parts = append(parts, result.Text)
+		sources = append(sources, newSource(result))
+	}
+	if len(sources) == 0 {
+		return nil, errors.New("rag: no passage found for the question")
+	}
+	context := strings.Join(parts, "\n\n")
+
+	answer := &Answer{Sources: sources, Start: -1, End: -1}
+	if p.prompt == nil {
+		result, err := p.params.QuestionAnswerer.Question(nlpcloud.QuestionParams{Question: question, Context: &context}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		answer.Answer, answer.Score = result.Answer, result.Score
+		if !answer.locateOffsets(offsets, result.Start, result.End) {
+			answer.locateText()
+		}
+		return answer, nil
+	}
+
+	var prompt bytes.Buffer
+	if err = p.prompt.Execute(&prompt, struct{ Question, Context string }{question, context}); err != nil {
+		return nil, err
+	}
+	params := p.params.Generation
+	params.Text = prompt.String()
+	generation, err := p.params.Generator.Generation(params, opts...)
+	if err != nil {
+		return nil, err
+	}
+	answer.Answer = strings.TrimSpace(generation.GeneratedText)
+	answer.locateText()
+	return answer, nil
+}
+
+// locateOffsets maps the character offsets of the answer in the context
+// back to its document, and reports whether they match the answer.
+func (a *Answer) locateOffsets(offsets []int, start, end int) bool {
+	for i := len(offsets) - 1; i >= 0; i-- {
+		if start < offsets[i] {
+			continue
+		}
+		source := a.Sources[i]
+		from := byteOffset(source.Text, start-offsets[i])
+		to := byteOffset(source.Text, end-offsets[i])
+		if from < 0 || to < from || source.Text[from:to] != a.Answer {
+			return false
+		}
+		a.DocumentID, a.Start, a.End = source.DocumentID, source.Start+from, source.Start+to
+		return true
+	}
+	return false
+}
+
+// locateText looks for the answer in the sources.
+func (a *Answer) locateText() {
+	if a.Answer == "" {
+		return
+	}
+	for _, source := range a.Sources {
+		if i := strings.Index(source.Text, a.Answer); i >= 0 {
+			a.DocumentID, a.Start, a.End = source.DocumentID, source.Start+i, source.Start+i+len(a.Answer)
+			return
+		}
+	}
+}
+
+// byteOffset converts a character offset of text into a byte offset, or
+// returns -1 if it is out of text.
+func byteOffset(text string, chars int) int {
+	if chars < 0 {
+		return -1
+	}
+	offset := 0
+	for ; chars > 0; chars-- {
+		if offset >= len(text) {
+			return -1
+		}
+		_, size := utf8.DecodeRuneInString(text[offset:])
+		offset += size
+	}
+	return offset
+}
+
+func newSource(result vectorindex.Result) Source {
+	source := Source{Text: result.Text, Score: result.Score, Metadata: result.Metadata}
+	source.DocumentID, _ = result.Metadata[MetadataDocumentID].(string)
+	source.Start = intValue(result.Metadata[MetadataStart])
+	source.End = intValue(result.Metadata[MetadataEnd])
+	return source
+}
+
+// intValue returns a metadata integer, which is a float64 once the index
+// is loaded from disk.
+func intValue(value interface{}) int {
+	switch value := value.(type) {
+	case int:
+		return value
+	case float64:
+		return int(value)
+	}
+	return 0
+}
@@ -0,0 +1,236 @@
+package rag
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudfake"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+	"github.com/nlpcloud/nlpcloud-go/vectorindex"
+)
+
+// guide is a document of two passages with multi-byte characters.
+const guide = "Le café coûte 3 €, le thé 2 €.\n\nLa tour Eiffel est à Paris, en France."
+
+// newEmbedder returns a client of a fake embeddings model, embedding the
+// texts about the tower close to the questions about it, after the ones
+// about drinks.
+func newEmbedder(t *testing.T) (*nlpcloud.Client, *nlpcloudtest.Server) {
+	t.Helper()
+	server := nlpcloudtest.NewServer("token")
+	t.Cleanup(server.Close)
+	server.Handle("embeddings", func(req nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+		var params nlpcloud.EmbeddingsParams
+		if err := req.Decode(&params); err != nil {
+			return nlpcloudtest.Error(http.StatusBadRequest, err.Error())
+		}
+		embeddings := make([][]float64, len(params.Sentences))
+		for i, sentence := range params.Sentences {
+			switch {
+			case strings.HasSuffix(sentence, "?"):
+				embeddings[i] = []float64{1, 1}
+			case strings.Contains(sentence, "café"):
+				embeddings[i] = []float64{1, 0.2}
+			default:
+				embeddings[i] = []float64{0.1, 1}
+			}
+		}
+		return nlpcloudtest.JSON(http.StatusOK, nlpcloud.Embeddings{Embeddings: embeddings})
+	})
+	return server.NewClient(nlpcloud.ClientParams{Model: "paraphrase-multilingual-mpnet-base-v2"}), server
+}
+
+// countWords counts the tokens of a text as its words.
+func countWords(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestNew(t *testing.T) {
+	embedder, _ := newEmbedder(t)
+	index := vectorindex.New(vectorindex.Params{})
+	if _, err := New(embedder, index, Params{}); err == nil {
+		t.Error("got no error without QuestionAnswerer")
+	}
+	if _, err := New(embedder, index, Params{PromptTemplate: "{{.Question}}", QuestionAnswerer: &nlpcloudfake.Fake{}}); err == nil {
+		t.Error("got no error with PromptTemplate and without Generator")
+	}
+	if _, err := New(embedder, index, Params{PromptTemplate: "{{.Question", Generator: &nlpcloudfake.Fake{}}); err == nil {
+		t.Error("got no error with an invalid PromptTemplate")
+	}
+}
+
+func TestAsk(t *testing.T) {
+	embedder, server := newEmbedder(t)
+	// The answerer finds "Paris" in the context, and returns its offsets in
+	// characters, like the API
+	answerer := &nlpcloudfake.Fake{}
+	answerer.Handle("Question", func(params interface{}) (interface{}, error) {
+		context := *params.(nlpcloud.QuestionParams).Context
+		start := utf8.RuneCountInString(context[:strings.Index(context, "Paris")])
+		return &nlpcloud.Question{Answer: "Paris", Score: 0.9, Start: start, End: start + 5}, nil
+	})
+	pipeline, err := New(embedder, vectorindex.New(vectorindex.Params{}), Params{
+		PassageTokens:    10,
+		CountTokens:      countWords,
+		QuestionAnswerer: answerer,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pipeline.Add([]Document{{ID: "guide", Text: guide, Metadata: map[string]interface{}{"lang": "fr"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := pipeline.Ask("Où est la tour Eiffel ?", vectorindex.Equals("lang", "fr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answer.Sources) != 2 || !strings.HasPrefix(answer.Sources[0].Text, "Le café") {
+		t.Fatalf("got sources %+v, want the café passage first", answer.Sources)
+	}
+	for _, source := range answer.Sources {
+		if guide[source.Start:source.End] != source.Text || source.Metadata["lang"] != "fr" {
+			t.Errorf("got source %+v out of its document", source)
+		}
+	}
+	if answer.DocumentID != "guide" || answer.Start < 0 || guide[answer.Start:answer.End] != "Paris" || answer.Score != 0.9 {
+		t.Errorf("got answer %+v", answer)
+	}
+	if n := len(server.RequestsFor("embeddings")); n != 2 {
+		t.Errorf("got %d embeddings requests, want 2", n)
+	}
+}
+
+func TestAskGeneration(t *testing.T) {
+	embedder, _ := newEmbedder(t)
+	generator := &nlpcloudfake.Fake{}
+	generator.Script("Generation", &nlpcloud.Generation{GeneratedText: " Paris\n"}, nil)
+	pipeline, err := New(embedder, vectorindex.New(vectorindex.Params{}), Params{
+		PassageTokens:  10,
+		CountTokens:    countWords,
+		PromptTemplate: "Context: {{.Context}}\nQuestion: {{.Question}}\nAnswer:",
+		Generator:      generator,
+		Generation:     nlpcloud.GenerationParams{MaxLength: intPtr(20)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = pipeline.Add([]Document{{ID: "guide", Text: guide}}); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := pipeline.Ask("Où est la tour Eiffel ?", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if answer.Answer != "Paris" || answer.DocumentID != "guide" || guide[answer.Start:answer.End] != "Paris" {
+		t.Errorf("got answer %+v", answer)
+	}
+	calls := generator.CallsTo("Generation")
+	if len(calls) != 1 {
+		t.Fatalf("got %d generations, want 1", len(calls))
+	}
+	params := calls[0].Params.(nlpcloud.GenerationParams)
+	if !strings.HasPrefix(params.Text, "Context: Le café") || !strings.HasSuffix(params.Text, "Question: Où est la tour Eiffel ?\nAnswer:") || *params.MaxLength != 20 {
+		t.Errorf("got generation %+v", params)
+	}
+}
+
+func TestAddAgain(t *testing.T) {
+	embedder, _ := newEmbedder(t)
+	index := vectorindex.New(vectorindex.Params{})
+	pipeline, err := New(embedder, index, Params{PassageTokens: 10, CountTokens: countWords, QuestionAnswerer: &nlpcloudfake.Fake{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	long := strings.Repeat("Une phrase de plusieurs mots.\n\n", 5)
+	if err = pipeline.Add([]Document{{ID: "doc", Text: long}, {ID: "other", Text: guide}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := index.Get("doc#2"); !ok {
+		t.Fatal("the long document has less than 3 passages")
+	}
+
+	// The passages of the longer version are all removed
+	if err = pipeline.Add([]Document{{ID: "doc", Text: "Une phrase."}}); err != nil {
+		t.Fatal(err)
+	}
+	if want := 1 + len(nlpcloud.SplitText(guide, 10, countWords)); index.Len() != want {
+		t.Errorf("got %d passages, want %d", index.Len(), want)
+	}
+	if item, ok := index.Get("doc#0"); !ok || item.Text != "Une phrase." {
+		t.Errorf("got passage %+v", item)
+	}
+	if _, ok := index.Get("doc#1"); ok {
+		t.Error("a passage of the previous version is still indexed")
+	}
+	if _, ok := index.Get("other#1"); !ok {
+		t.Error("the passages of the other documents were removed")
+	}
+}
+
+func TestByteOffset(t *testing.T) {
+	tests := []struct {
+		text  string
+		chars int
+		want  int
+	}{
+		{text: "hello", chars: 0, want: 0},
+		{text: "hello", chars: 5, want: 5},
+		{text: "hello", chars: 6, want: -1},
+		{text: "hello", chars: -1, want: -1},
+		{text: "café crème", chars: 4, want: 5},
+		{text: "café crème", chars: 10, want: 12},
+		{text: "3 € 日本", chars: 4, want: 6},
+		{text: "a🎉b", chars: 2, want: 5},
+	}
+	for _, test := range tests {
+		if got := byteOffset(test.text, test.chars); got != test.want {
+			t.Errorf("byteOffset(%q, %d): got %d, want %d", test.text, test.chars, got, test.want)
+		}
+	}
+}
+
+func TestLocateOffsets(t *testing.T) {
+	// The sources are at bytes 100 and 40 of their documents, and the
+	// context is "Prix : 3 €.\n\nÀ Zürich, il neige."
+	sources := []Source{
+		{DocumentID: "prices", Text: "Prix : 3 €.", Start: 100, End: 113},
+		{DocumentID: "weather", Text: "À Zürich, il neige.", Start: 40, End: 61},
+	}
+	offsets := []int{0, 13}
+	tests := []struct {
+		name       string
+		answer     string
+		start, end int
+		ok         bool
+		documentID string
+		want       [2]int
+	}{
+		{name: "first source", answer: "3 €", start: 7, end: 10, ok: true, documentID: "prices", want: [2]int{107, 112}},
+		{name: "second source", answer: "Zürich", start: 15, end: 21, ok: true, documentID: "weather", want: [2]int{43, 50}},
+		{name: "second source start", answer: "À", start: 13, end: 14, ok: true, documentID: "weather", want: [2]int{40, 42}},
+		{name: "wrong offsets", answer: "Zürich", start: 14, end: 20},
+		{name: "across sources", answer: "€.\n\nÀ", start: 9, end: 14},
+		{name: "out of the context", answer: "neige", start: 40, end: 45},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			answer := &Answer{Answer: test.answer, Sources: sources, Start: -1, End: -1}
+			ok := answer.locateOffsets(offsets, test.start, test.end)
+			if ok != test.ok {
+				t.Fatalf("got %v, want %v", ok, test.ok)
+			}
+			if ok && (answer.DocumentID != test.documentID || answer.Start != test.want[0] || answer.End != test.want[1]) {
+				t.Errorf("got %s %d-%d, want %s %d-%d", answer.DocumentID, answer.Start, answer.End, test.documentID, test.want[0], test.want[1])
+			}
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
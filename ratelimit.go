@@ -0,0 +1,114 @@
+package nlpcloud
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter defines what a rate limiter have to implement in order to
+// throttle the requests issued by the Client.
+type RateLimiter interface {
+	// Wait blocks until a request to endpoint is allowed, or until ctx
+	// is done.
+	Wait(ctx context.Context, endpoint string) error
+}
+
+// TokenBucket is a RateLimiter allowing a sustained number of requests
+// per second, with bursts up to a given size.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// Makes sure the *TokenBucket works with the RateLimiter.
+var _ RateLimiter = (*TokenBucket)(nil)
+
+// NewTokenBucket initializes a new TokenBucket allowing requestsPerSecond
+// requests per second, with bursts up to burst requests.
+// The bucket starts full. A requestsPerSecond lower or equal to 0 disables
+// the throttling.
+func NewTokenBucket(requestsPerSecond float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   requestsPerSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available, or until ctx is done.
+// The endpoint is ignored.
+func (b *TokenBucket) Wait(ctx context.Context, endpoint string) error {
+	if b.rate <= 0 {
+		return nil
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		delay := b.reserve(time.Now())
+		if delay == 0 {
+			return nil
+		}
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token if one is available and returns 0, or returns
+// the delay until the next token is available.
+func (b *TokenBucket) reserve(now time.Time) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	delay := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if delay <= 0 {
+		delay = time.Millisecond
+	}
+	return delay
+}
+
+// EndpointRateLimiter is a RateLimiter applying a dedicated limiter per
+// endpoint (e.g. "generation", "embeddings"), and falling back to Default
+// for the other endpoints.
+type EndpointRateLimiter struct {
+	// Default is used for the endpoints missing from Endpoints.
+	// If nil, those endpoints are not throttled.
+	Default RateLimiter
+	// Endpoints holds the limiter for each endpoint name.
+	Endpoints map[string]RateLimiter
+}
+
+// Makes sure the *EndpointRateLimiter works with the RateLimiter.
+var _ RateLimiter = (*EndpointRateLimiter)(nil)
+
+// Wait blocks until the limiter of endpoint allows the request, or until
+// ctx is done.
+func (l *EndpointRateLimiter) Wait(ctx context.Context, endpoint string) error {
+	if limiter, ok := l.Endpoints[endpoint]; ok && limiter != nil {
+		return limiter.Wait(ctx, endpoint)
+	}
+	if l.Default != nil {
+		return l.Default.Wait(ctx, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package nlpcloud
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how a failed request is retried.
+// The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	// A value lower than 2 disables retries.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles on each
+	// following attempt.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay before a retry, including the delay asked by
+	// a Retry-After header: a longer Retry-After is shortened to MaxDelay.
+	// Zero means no cap.
+	MaxDelay time.Duration
+	// Jitter is the fraction of the delay (between 0 and 1) that is
+	// randomized, in order to avoid synchronized retries.
+	Jitter float64
+	// RetryableStatusCodes lists the HTTP status codes that trigger a retry.
+	RetryableStatusCodes []int
+	// IsRetryableError reports whether a transport error triggers a retry.
+	// Default is IsRetryableNetworkError.
+	IsRetryableError func(error) bool
+}
+
+// DefaultRetryPolicy returns a RetryPolicy that retries up to 3 times
+// on rate limiting, server errors and transient network errors.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 4,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusInternalServerError,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		IsRetryableError: IsRetryableNetworkError,
+	}
+}
+
+// IsRetryableNetworkError reports whether err is a transient network
+// error, like a timeout or a reset connection.
+// Context cancellation is never considered retryable.
+func IsRetryableNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) retryableStatus(status int) bool {
+	if p == nil {
+		return false
+	}
+	for _, code := range p.RetryableStatusCodes {
+		if code == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryableError(err error) bool {
+	if p == nil {
+		return false
+	}
+	if p.IsRetryableError != nil {
+		return p.IsRetryableError(err)
+	}
+	return IsRetryableNetworkError(err)
+}
+
+// backoff returns the delay to wait before the given retry (starting at 1).
+func (p *RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.BaseDelay) * math.Pow(2, float64(retry-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1)
+		delay = delay*(1-jitter) + delay*jitter*rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is
+// either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
+
+// retryable reports whether the failed request may be issued again.
+func (p *RetryPolicy) retryable(err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return p.retryableStatus(httpErr.Status)
+	}
+	return p.retryableError(err)
+}
+
+// retryDelay returns the delay before the given retry. The Retry-After
+// header is honored on 429 and 503 responses, up to MaxDelay.
+func (p *RetryPolicy) retryDelay(retry int, err error) time.Duration {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) && httpErr.RetryAfter > 0 &&
+		(httpErr.Status == http.StatusTooManyRequests || httpErr.Status == http.StatusServiceUnavailable) {
+		if p.MaxDelay > 0 && httpErr.RetryAfter > p.MaxDelay {
+			return p.MaxDelay
+		}
+		return httpErr.RetryAfter
+	}
+	return p.backoff(retry)
+}
+
+// sleepContext waits for the given delay, or until ctx is done.
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// doWithRetry issues the request with handler, retrying according to the
+// options policy. Each attempt waits on the client and options rate
+// limiters.
+func (c *Client) doWithRetry(req *Request, options *options, handler Handler) (*Response, error) {
+	ctx := req.Context()
+	policy := options.Retry
+	attempts := policy.attempts()
+	for attempt := 1; ; attempt++ {
+		for _, limiter := range []RateLimiter{c.limiter, options.limiter} {
+			if limiter == nil {
+				continue
+			}
+			if err := limiter.Wait(ctx, req.Endpoint); err != nil {
+				return nil, err
+			}
+		}
+
+		// Each attempt gets its own copy of the request, so middlewares
+		// mutating it do not leak into the next attempts.
+		attemptReq := *req
+		attemptReq.Attempt = attempt
+		attemptReq.Header = req.Header.Clone()
+		if attemptReq.Header == nil {
+			attemptReq.Header = http.Header{}
+		}
+
+		resp, err := handler(&attemptReq)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= attempts || !policy.retryable(err) {
+			return nil, err
+		}
+
+		if err := sleepContext(ctx, policy.retryDelay(attempt, err)); err != nil {
+			return nil, err
+		}
+	}
+}
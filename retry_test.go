@@ -0,0 +1,163 @@
+package nlpcloud
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubHTTPClient is a HTTPClient answering the requests with a function.
+type stubHTTPClient func(*http.Request) (*http.Response, error)
+
+// Makes sure the stubHTTPClient works with the HTTPClient.
+var _ HTTPClient = stubHTTPClient(nil)
+
+func (f stubHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// stubResponse returns a response with the given status, body and headers
+// (as key-value pairs).
+func stubResponse(status int, body string, header ...string) *http.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		resp.Header.Set(header[i], header[i+1])
+	}
+	return resp
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range want {
+		if got := policy.backoff(i + 1); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.backoff(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("backoff(2) with jitter = %v, want between 100ms and 200ms", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "3", want: 3 * time.Second, ok: true},
+		{value: " 120 ", want: 2 * time.Minute, ok: true},
+		{value: "0", want: 0, ok: true},
+		{value: now.Add(30 * time.Second).Format(http.TimeFormat), want: 30 * time.Second, ok: true},
+		{value: now.Add(-time.Minute).Format(http.TimeFormat), want: 0, ok: true},
+		{value: "Tue, 02 Jan 2024 15:05:05 GMT", want: time.Minute, ok: true},
+		{value: "", ok: false},
+		{value: "-1", ok: false},
+		{value: "soon", ok: false},
+	}
+	for _, test := range tests {
+		got, ok := parseRetryAfter(test.value, now)
+		if got != test.want || ok != test.ok {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", test.value, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	tests := []struct {
+		name string
+		err  error
+		want time.Duration
+	}{
+		{name: "retry after", err: &HTTPError{Status: http.StatusTooManyRequests, RetryAfter: 2 * time.Second}, want: 2 * time.Second},
+		{name: "retry after capped", err: &HTTPError{Status: http.StatusServiceUnavailable, RetryAfter: time.Hour}, want: 5 * time.Second},
+		{name: "retry after ignored", err: &HTTPError{Status: http.StatusInternalServerError, RetryAfter: 2 * time.Second}, want: 2 * time.Second},
+		{name: "backoff", err: errors.New("connection reset"), want: 2 * time.Second},
+	}
+	for _, test := range tests {
+		if got := policy.retryDelay(2, test.err); got != test.want {
+			t.Errorf("%s: retryDelay = %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	policy.MaxDelay = 0
+	err := &HTTPError{Status: http.StatusTooManyRequests, RetryAfter: time.Hour}
+	if got := policy.retryDelay(1, err); got != time.Hour {
+		t.Errorf("retryDelay without MaxDelay = %v, want %v", got, time.Hour)
+	}
+}
+
+func TestRetryRebuildsBody(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	client := NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, string(body))
+		switch len(bodies) {
+		case 1:
+			return stubResponse(http.StatusTooManyRequests, `{"detail":"slow down"}`, "Retry-After", "0"), nil
+		case 2:
+			return stubResponse(http.StatusServiceUnavailable, `{"detail":"unavailable"}`), nil
+		}
+		return stubResponse(http.StatusOK, `{"scored_labels":[{"label":"POSITIVE","score":0.9}]}`), nil
+	}), ClientParams{Model: "model", Token: "token", Retry: &RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusTooManyRequests, http.StatusServiceUnavailable},
+	}})
+
+	sentiment, err := client.Sentiment(SentimentParams{Text: "I love it"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sentiment.ScoredLabels) != 1 || sentiment.ScoredLabels[0].Label != "POSITIVE" {
+		t.Errorf("unexpected sentiment: %+v", sentiment)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("got %d attempts, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		if body != `{"text":"I love it"}` {
+			t.Errorf("attempt %d sent body %q", i+1, body)
+		}
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	attempts := 0
+	client := NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return stubResponse(http.StatusBadRequest, `{"detail":"bad request"}`), nil
+	}), ClientParams{Model: "model", Token: "token", Retry: &RetryPolicy{
+		MaxAttempts:          3,
+		BaseDelay:            time.Millisecond,
+		RetryableStatusCodes: []int{http.StatusServiceUnavailable},
+	}})
+
+	_, err := client.Sentiment(SentimentParams{Text: "I love it"})
+	if !errors.Is(err, ErrBadRequest) {
+		t.Errorf("got error %v, want ErrBadRequest", err)
+	}
+	if attempts != 1 {
+		t.Errorf("got %d attempts, want 1", attempts)
+	}
+}
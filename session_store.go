@@ -0,0 +1,424 @@
+package nlpcloud
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned when loading a session that does not
+// exist or expired.
+var ErrSessionNotFound = errors.New("session not found")
+
+// ErrSessionConflict is returned when saving a session modified since it
+// was loaded, e.g. by a concurrent turn on another replica.
+var ErrSessionConflict = errors.New("session modified concurrently")
+
+// SessionRecord holds the persisted state of a chat session.
+type SessionRecord struct {
+	Context string     `json:"context,omitempty"`
+	Summary string     `json:"summary,omitempty"`
+	History []Exchange `json:"history"`
+	// Version is incremented on each save. It is 0 for a new session.
+	Version   uint64    `json:"version"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SessionStore defines what a store have to implement in order to persist
+// chat sessions. It must be safe for concurrent use.
+type SessionStore interface {
+	// Load returns the record of the session id, or ErrSessionNotFound.
+	Load(ctx context.Context, id string) (*SessionRecord, error)
+	// Save stores the record of the session id if its Version matches the
+	// stored one, and increments it. Otherwise, ErrSessionConflict is
+	// returned.
+	Save(ctx context.Context, id string, record *SessionRecord) error
+	// Delete removes the session id.
+	Delete(ctx context.Context, id string) error
+}
+
+// SessionStoreParams wraps the parameters of the session stores.
+type SessionStoreParams struct {
+	// TTL expires the sessions not saved for this duration.
+	// Zero means no expiry.
+	TTL time.Duration
+	// Redact is applied to the context, summary and exchanges before they
+	// are persisted, e.g. to remove PII.
+	Redact func(text string) string
+}
+
+func (p SessionStoreParams) expired(record *SessionRecord) bool {
+	return p.TTL > 0 && time.Since(record.UpdatedAt) > p.TTL
+}
+
+// prepare checks the version of record against the stored one, and
+// returns the redacted record to persist.
+func (p SessionStoreParams) prepare(stored, record *SessionRecord) (*SessionRecord, error) {
+	version := uint64(0)
+	if stored != nil && !p.expired(stored) {
+		version = stored.Version
+	}
+	if record.Version != version {
+		return nil, ErrSessionConflict
+	}
+
+	persisted := &SessionRecord{
+		Context:   record.Context,
+		Summary:   record.Summary,
+		History:   append([]Exchange{}, record.History...),
+		Version:   version + 1,
+		UpdatedAt: time.Now(),
+	}
+	if p.Redact != nil {
+		persisted.Context = p.Redact(persisted.Context)
+		persisted.Summary = p.Redact(persisted.Summary)
+		for i, exchange := range persisted.History {
+			persisted.History[i] = Exchange{Input: p.Redact(exchange.Input), Response: p.Redact(exchange.Response)}
+		}
+	}
+	return persisted, nil
+}
+
+// FileSessionStore is a SessionStore storing each session in a file of a
+// directory. The directory may be shared by several processes.
+type FileSessionStore struct {
+	dir    string
+	params SessionStoreParams
+}
+
+// Makes sure the *FileSessionStore works with the SessionStore.
+var _ SessionStore = (*FileSessionStore)(nil)
+
+// NewFileSessionStore initializes a new FileSessionStore in dir, creating
+// it if needed.
+func NewFileSessionStore(dir string, params SessionStoreParams) (*FileSessionStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileSessionStore{dir: dir, params: params}, nil
+}
+
+// Load returns the record of the session id, or ErrSessionNotFound.
+func (f *FileSessionStore) Load(ctx context.Context, id string) (*SessionRecord, error) {
+	record, err := f.read(id)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil || f.params.expired(record) {
+		return nil, ErrSessionNotFound
+	}
+	return record, nil
+}
+
+// Save stores the record of the session id if its Version matches the
+// stored one, and increments it.
+func (f *FileSessionStore) Save(ctx context.Context, id string, record *SessionRecord) error {
+	unlock, err := f.lock(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := f.read(id)
+	if err != nil {
+		return err
+	}
+	persisted, err := f.params.prepare(stored, record)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	if err = writeFileAtomic(f.path(id), data); err != nil {
+		return err
+	}
+	record.Version, record.UpdatedAt = persisted.Version, persisted.UpdatedAt
+	return nil
+}
+
+// Delete removes the session id.
+func (f *FileSessionStore) Delete(ctx context.Context, id string) error {
+	unlock, err := f.lock(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err = os.Remove(f.path(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired removes the expired sessions, and returns their number.
+func (f *FileSessionStore) DeleteExpired() (int, error) {
+	if f.params.TTL <= 0 {
+		return 0, nil
+	}
+	paths, err := filepath.Glob(filepath.Join(f.dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	deleted := 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || time.Since(info.ModTime()) <= f.params.TTL {
+			continue
+		}
+		if os.Remove(path) == nil {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// read returns the stored record of the session id, or nil.
+func (f *FileSessionStore) read(id string) (*SessionRecord, error) {
+	data, err := os.ReadFile(f.path(id))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := &SessionRecord{}
+	if err = json.Unmarshal(data, record); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// lock acquires the lock file of the session id, shared by the processes
+// using the directory. Locks older than 10 seconds are considered stale.
+func (f *FileSessionStore) lock(ctx context.Context, id string) (func(), error) {
+	path := f.path(id) + ".lock"
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > 10*time.Second {
+			os.Remove(path)
+			continue
+		}
+		if err := sleepContext(ctx, 10*time.Millisecond); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *FileSessionStore) path(id string) string {
+	hash := sha256.Sum256([]byte(id))
+	return filepath.Join(f.dir, hex.EncodeToString(hash[:])+".json")
+}
+
+// writeFileAtomic writes data to a temporary file renamed to path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// KVSessionStore is a SessionStore keeping the sessions in memory, and
+// persisting them in a single append-only file, compacted when it grows.
+// The file must only be opened by one process at a time.
+type KVSessionStore struct {
+	params SessionStoreParams
+
+	mu       sync.Mutex
+	path     string
+	file     *os.File
+	records  map[string]*SessionRecord
+	appended int
+}
+
+// Makes sure the *KVSessionStore works with the SessionStore.
+var _ SessionStore = (*KVSessionStore)(nil)
+
+// kvEntry is a line of the KVSessionStore file. A nil record deletes the
+// session.
+type kvEntry struct {
+	ID     string         `json:"id"`
+	Record *SessionRecord `json:"record,omitempty"`
+}
+
+// OpenKVSessionStore opens the KVSessionStore stored at path, creating it
+// if needed.
+func OpenKVSessionStore(path string, params SessionStoreParams) (*KVSessionStore, error) {
+	store := &KVSessionStore{params: params, path: path, records: map[string]*SessionRecord{}}
+
+	file, err := os.Open(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		reader := bufio.NewReader(file)
+		for {
+			line, err := reader.ReadBytes('\n')
+			var entry kvEntry
+			// A line cut when the process was killed is ignored
+			if json.Unmarshal(line, &entry) == nil {
+				if entry.Record == nil {
+					delete(store.records, entry.ID)
+				} else {
+					store.records[entry.ID] = entry.Record
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		file.Close()
+	}
+
+	// Compact the file, dropping the deleted and expired sessions
+	if err = store.compact(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+// Load returns the record of the session id, or ErrSessionNotFound.
+func (k *KVSessionStore) Load(ctx context.Context, id string) (*SessionRecord, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	record, ok := k.records[id]
+	if !ok || k.params.expired(record) {
+		return nil, ErrSessionNotFound
+	}
+	loaded := *record
+	loaded.History = append([]Exchange(nil), record.History...)
+	return &loaded, nil
+}
+
+// Save stores the record of the session id if its Version matches the
+// stored one, and increments it.
+func (k *KVSessionStore) Save(ctx context.Context, id string, record *SessionRecord) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	persisted, err := k.params.prepare(k.records[id], record)
+	if err != nil {
+		return err
+	}
+	if err = k.append(kvEntry{ID: id, Record: persisted}); err != nil {
+		return err
+	}
+	k.records[id] = persisted
+	record.Version, record.UpdatedAt = persisted.Version, persisted.UpdatedAt
+	k.compactIfGrown()
+	return nil
+}
+
+// Delete removes the session id.
+func (k *KVSessionStore) Delete(ctx context.Context, id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.records[id]; !ok {
+		return nil
+	}
+	if err := k.append(kvEntry{ID: id}); err != nil {
+		return err
+	}
+	delete(k.records, id)
+	k.compactIfGrown()
+	return nil
+}
+
+// Close closes the store file.
+func (k *KVSessionStore) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.file.Close()
+}
+
+// append writes an entry to the file. k.mu must be held.
+func (k *KVSessionStore) append(entry kvEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = k.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	k.appended++
+	return nil
+}
+
+// compactIfGrown compacts the file once it holds twice more entries than
+// sessions. It must be called once k.records holds the appended entries,
+// which are rewritten from it. k.mu must be held.
+func (k *KVSessionStore) compactIfGrown() {
+	if k.appended > 2*len(k.records)+64 {
+		// The entries are already appended, so a failed compaction loses
+		// nothing, and is tried again on the next change
+		k.compact()
+	}
+}
+
+// compact rewrites the file with the live sessions only. The file is only
+// replaced once fully written, so the store keeps appending to the previous
+// one on error. k.mu must be held, unless the store is being opened.
+func (k *KVSessionStore) compact() error {
+	var data []byte
+	for id, record := range k.records {
+		if k.params.expired(record) {
+			delete(k.records, id)
+			continue
+		}
+		line, err := json.Marshal(kvEntry{ID: id, Record: record})
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+
+	// The temporary file is kept open to append to it once renamed
+	file, err := os.CreateTemp(filepath.Dir(k.path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err == nil {
+		err = os.Rename(file.Name(), k.path)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+	if k.file != nil {
+		k.file.Close()
+	}
+	k.file = file
+	k.appended = len(k.records)
+	return nil
+}
@@ -0,0 +1,231 @@
+package nlpcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// sessionStores returns the session stores to test, opened in a
+// temporary directory with params.
+func sessionStores(t *testing.T, params SessionStoreParams) map[string]SessionStore {
+	t.Helper()
+	files, err := NewFileSessionStore(t.TempDir(), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kv, err := OpenKVSessionStore(filepath.Join(t.TempDir(), "sessions"), params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { kv.Close() })
+	return map[string]SessionStore{"file": files, "kv": kv}
+}
+
+func TestSessionStoreVersions(t *testing.T) {
+	ctx := context.Background()
+	for name, store := range sessionStores(t, SessionStoreParams{}) {
+		t.Run(name, func(t *testing.T) {
+			if _, err := store.Load(ctx, "id"); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("got error %v, want ErrSessionNotFound", err)
+			}
+
+			record := &SessionRecord{Context: "context", History: []Exchange{{Input: "Hi", Response: "Hello"}}}
+			if err := store.Save(ctx, "id", record); err != nil {
+				t.Fatal(err)
+			}
+			if record.Version != 1 {
+				t.Errorf("got version %d, want 1", record.Version)
+			}
+			loaded, err := store.Load(ctx, "id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Version != 1 || loaded.Context != "context" || len(loaded.History) != 1 || loaded.History[0].Response != "Hello" {
+				t.Errorf("got record %+v", loaded)
+			}
+
+			// A replica saving the version it loaded wins, the other one
+			// conflicts
+			stale := *loaded
+			loaded.History = append(loaded.History, Exchange{Input: "How are you?", Response: "Fine"})
+			if err = store.Save(ctx, "id", loaded); err != nil {
+				t.Fatal(err)
+			}
+			if err = store.Save(ctx, "id", &stale); !errors.Is(err, ErrSessionConflict) {
+				t.Errorf("got error %v, want ErrSessionConflict", err)
+			}
+			if err = store.Save(ctx, "other", &SessionRecord{Version: 3}); !errors.Is(err, ErrSessionConflict) {
+				t.Errorf("got error %v for a new session, want ErrSessionConflict", err)
+			}
+			if loaded, err = store.Load(ctx, "id"); err != nil || loaded.Version != 2 || len(loaded.History) != 2 {
+				t.Errorf("got record %+v, %v", loaded, err)
+			}
+
+			if err = store.Delete(ctx, "id"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err = store.Load(ctx, "id"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("got error %v after delete, want ErrSessionNotFound", err)
+			}
+			if err = store.Delete(ctx, "id"); err != nil {
+				t.Errorf("got error %v deleting a missing session", err)
+			}
+			if err = store.Save(ctx, "id", &SessionRecord{}); err != nil {
+				t.Errorf("got error %v saving a deleted session again", err)
+			}
+		})
+	}
+}
+
+func TestSessionStoreTTL(t *testing.T) {
+	ctx := context.Background()
+	const ttl = 50 * time.Millisecond
+	for name, store := range sessionStores(t, SessionStoreParams{TTL: ttl}) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.Save(ctx, "expired", &SessionRecord{Summary: "old"}); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(2 * ttl)
+			if err := store.Save(ctx, "live", &SessionRecord{}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Load(ctx, "expired"); !errors.Is(err, ErrSessionNotFound) {
+				t.Errorf("got error %v, want ErrSessionNotFound", err)
+			}
+			if _, err := store.Load(ctx, "live"); err != nil {
+				t.Errorf("got error %v for a live session", err)
+			}
+
+			// An expired session starts over at version 0
+			record := &SessionRecord{}
+			if err := store.Save(ctx, "expired", record); err != nil || record.Version != 1 {
+				t.Errorf("got version %d, %v, want 1", record.Version, err)
+			}
+		})
+	}
+}
+
+func TestFileSessionStoreDeleteExpired(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileSessionStore(t.TempDir(), SessionStoreParams{TTL: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b"} {
+		if err = store.Save(ctx, id, &SessionRecord{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err = store.Save(ctx, "c", &SessionRecord{}); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := store.DeleteExpired(); err != nil || n != 2 {
+		t.Errorf("got %d deleted sessions, %v, want 2", n, err)
+	}
+	if _, err = store.Load(ctx, "c"); err != nil {
+		t.Errorf("got error %v for a live session", err)
+	}
+}
+
+func TestSessionStoreRedact(t *testing.T) {
+	ctx := context.Background()
+	digits := regexp.MustCompile(`\d`)
+	params := SessionStoreParams{Redact: func(text string) string { return digits.ReplaceAllString(text, "#") }}
+	for name, store := range sessionStores(t, params) {
+		t.Run(name, func(t *testing.T) {
+			record := &SessionRecord{
+				Context: "Customer 42",
+				Summary: "Called on 2024-01-01",
+				History: []Exchange{{Input: "My card is 1234", Response: "Card 1234 noted"}},
+			}
+			if err := store.Save(ctx, "id", record); err != nil {
+				t.Fatal(err)
+			}
+			if record.History[0].Input != "My card is 1234" {
+				t.Errorf("the record of the caller was redacted: %+v", record)
+			}
+			loaded, err := store.Load(ctx, "id")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := SessionRecord{
+				Context: "Customer ##",
+				Summary: "Called on ####-##-##",
+				History: []Exchange{{Input: "My card is ####", Response: "Card #### noted"}},
+			}
+			if loaded.Context != want.Context || loaded.Summary != want.Summary || loaded.History[0] != want.History[0] {
+				t.Errorf("got record %+v, want %+v", loaded, want)
+			}
+		})
+	}
+}
+
+func TestKVSessionStoreCompaction(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sessions")
+	store, err := OpenKVSessionStore(path, SessionStoreParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save(ctx, "deleted", &SessionRecord{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Check the saves compacting the file keep their entry
+	record := &SessionRecord{}
+	compactions := 0
+	for turn := 0; turn < 300; turn++ {
+		compacting := store.appended+1 > 2*len(store.records)+64
+		record.History = append(record.History, Exchange{Input: fmt.Sprint("turn ", turn)})
+		if err = store.Save(ctx, "kept", record); err != nil {
+			t.Fatal(err)
+		}
+		if compacting {
+			compactions++
+			if store.appended != len(store.records) {
+				t.Fatalf("turn %d: the file was not compacted", turn)
+			}
+		}
+	}
+	if compactions == 0 {
+		t.Fatal("the file was never compacted")
+	}
+
+	// Make the deletion compact the file
+	for store.appended+1 <= 2*(len(store.records)-1)+64 {
+		if err = store.Save(ctx, "kept", record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = store.Delete(ctx, "deleted"); err != nil {
+		t.Fatal(err)
+	}
+	if store.appended != len(store.records) {
+		t.Fatal("the deletion did not compact the file")
+	}
+	if err = store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	store, err = OpenKVSessionStore(path, SessionStoreParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	loaded, err := store.Load(ctx, "kept")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version != record.Version || len(loaded.History) != 300 || loaded.History[299].Input != "turn 299" {
+		t.Errorf("got version %d with %d exchanges after reopening, want version %d with 300", loaded.Version, len(loaded.History), record.Version)
+	}
+	if _, err = store.Load(ctx, "deleted"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("got error %v for the deleted session, want ErrSessionNotFound", err)
+	}
+}
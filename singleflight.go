@@ -0,0 +1,102 @@
+package nlpcloud
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// flightGroup coalesces identical concurrent requests, so they share one
+// round trip and its response.
+type flightGroup struct {
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight is a request shared by several callers.
+type flight struct {
+	done    chan struct{}
+	resp    *Response
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: map[string]*flight{}}
+}
+
+// do calls fn once for all the concurrent callers with the same key, and
+// returns its response to each of them.
+//
+// fn runs with a context detached from the callers' cancellation, so a
+// caller giving up does not fail the others. It is cancelled once all the
+// callers have given up.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*Response, error)) (*Response, error) {
+	g.mu.Lock()
+	f, ok := g.flights[key]
+	if ok {
+		f.waiters++
+	} else {
+		flightCtx, cancel := context.WithCancel(detachedContext{ctx})
+		f = &flight{
+			done:    make(chan struct{}),
+			waiters: 1,
+			cancel:  cancel,
+		}
+		g.flights[key] = f
+		go g.run(key, f, flightCtx, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.resp, f.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		f.waiters--
+		if f.waiters == 0 {
+			f.cancel()
+			if g.flights[key] == f {
+				delete(g.flights, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) run(key string, f *flight, ctx context.Context, fn func(ctx context.Context) (*Response, error)) {
+	f.resp, f.err = fn(ctx)
+
+	g.mu.Lock()
+	if g.flights[key] == f {
+		delete(g.flights, key)
+	}
+	g.mu.Unlock()
+
+	f.cancel()
+	close(f.done)
+}
+
+// detachedContext keeps the values of its parent, but not its deadline
+// and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
@@ -0,0 +1,229 @@
+package nlpcloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingServer answers the sentiment requests once released, or fails
+// them when their context is cancelled.
+type blockingServer struct {
+	requests  int32
+	started   chan struct{}
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func newBlockingServer() *blockingServer {
+	return &blockingServer{
+		started:   make(chan struct{}, 1),
+		release:   make(chan struct{}),
+		cancelled: make(chan struct{}, 1),
+	}
+}
+
+func (s *blockingServer) client() *Client {
+	return NewClient(stubHTTPClient(func(req *http.Request) (*http.Response, error) {
+		atomic.AddInt32(&s.requests, 1)
+		s.started <- struct{}{}
+		select {
+		case <-s.release:
+			return stubResponse(http.StatusOK, `{"scored_labels":[{"label":"POSITIVE","score":0.9}]}`), nil
+		case <-req.Context().Done():
+			s.cancelled <- struct{}{}
+			return nil, req.Context().Err()
+		}
+	}), ClientParams{Model: "model", Token: "token", CoalesceRequests: true})
+}
+
+// waitWaiters waits until the flights of c have n callers in total.
+func waitWaiters(t *testing.T, c *Client, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.flights.mu.Lock()
+		waiters := 0
+		for _, f := range c.flights.flights {
+			waiters += f.waiters
+		}
+		c.flights.mu.Unlock()
+		if waiters == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d waiters, want %d", waiters, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type sentimentResult struct {
+	sentiment *Sentiment
+	err       error
+}
+
+func sentimentAsync(c *Client, ctx context.Context) <-chan sentimentResult {
+	result := make(chan sentimentResult, 1)
+	go func() {
+		sentiment, err := c.Sentiment(SentimentParams{Text: "I love it"}, WithContext(ctx))
+		result <- sentimentResult{sentiment, err}
+	}()
+	return result
+}
+
+func TestFlightLeaderCancels(t *testing.T) {
+	server := newBlockingServer()
+	client := server.client()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	leader := sentimentAsync(client, ctx)
+	<-server.started
+	followers := []<-chan sentimentResult{
+		sentimentAsync(client, context.Background()),
+		sentimentAsync(client, context.Background()),
+	}
+	waitWaiters(t, client, 3)
+
+	cancel()
+	if result := <-leader; !errors.Is(result.err, context.Canceled) {
+		t.Errorf("leader: got error %v, want context.Canceled", result.err)
+	}
+	waitWaiters(t, client, 2)
+
+	close(server.release)
+	for i, follower := range followers {
+		result := <-follower
+		if result.err != nil {
+			t.Errorf("follower %d: %v", i, result.err)
+		} else if result.sentiment.ScoredLabels[0].Label != "POSITIVE" {
+			t.Errorf("follower %d: got %+v", i, result.sentiment)
+		}
+	}
+	select {
+	case <-server.cancelled:
+		t.Errorf("the shared request was cancelled")
+	default:
+	}
+	if n := atomic.LoadInt32(&server.requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestFlightAllCallersCancel(t *testing.T) {
+	server := newBlockingServer()
+	client := server.client()
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	results := []<-chan sentimentResult{sentimentAsync(client, ctx1)}
+	<-server.started
+	results = append(results, sentimentAsync(client, ctx2))
+	waitWaiters(t, client, 2)
+
+	cancel1()
+	cancel2()
+	for i, result := range results {
+		if result := <-result; !errors.Is(result.err, context.Canceled) {
+			t.Errorf("caller %d: got error %v, want context.Canceled", i, result.err)
+		}
+	}
+	select {
+	case <-server.cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shared request was not cancelled")
+	}
+
+	// A new caller issues a new request
+	close(server.release)
+	result := <-sentimentAsync(client, context.Background())
+	<-server.started
+	if result.err != nil {
+		t.Fatal(result.err)
+	}
+	if n := atomic.LoadInt32(&server.requests); n != 2 {
+		t.Errorf("got %d requests, want 2", n)
+	}
+}
+
+func TestFlightResultCopies(t *testing.T) {
+	server := newBlockingServer()
+	client := server.client()
+
+	results := []<-chan sentimentResult{sentimentAsync(client, context.Background())}
+	<-server.started
+	for i := 0; i < 2; i++ {
+		results = append(results, sentimentAsync(client, context.Background()))
+	}
+	waitWaiters(t, client, 3)
+	close(server.release)
+
+	var sentiments []*Sentiment
+	for i, result := range results {
+		result := <-result
+		if result.err != nil {
+			t.Fatalf("caller %d: %v", i, result.err)
+		}
+		sentiments = append(sentiments, result.sentiment)
+	}
+	sentiments[0].ScoredLabels[0].Label = "NEGATIVE"
+	for i, sentiment := range sentiments[1:] {
+		if sentiment == sentiments[0] || sentiment.ScoredLabels[0].Label != "POSITIVE" {
+			t.Errorf("caller %d shares the result of caller 0", i+1)
+		}
+	}
+	if n := atomic.LoadInt32(&server.requests); n != 1 {
+		t.Errorf("got %d requests, want 1", n)
+	}
+}
+
+func TestFlightCallOptions(t *testing.T) {
+	server := newBlockingServer()
+	client := server.client()
+
+	results := []<-chan sentimentResult{sentimentAsync(client, context.Background())}
+	<-server.started
+	// The requests with their own options cannot share the round trip
+	for _, opt := range []Option{WithRetryPolicy(DefaultRetryPolicy()), WithRateLimiter(NewTokenBucket(100, 1))} {
+		result := make(chan sentimentResult, 1)
+		go func(opt Option) {
+			sentiment, err := client.Sentiment(SentimentParams{Text: "I love it"}, opt)
+			result <- sentimentResult{sentiment, err}
+		}(opt)
+		results = append(results, result)
+		<-server.started
+	}
+	close(server.release)
+
+	for i, result := range results {
+		if result := <-result; result.err != nil {
+			t.Fatalf("caller %d: %v", i, result.err)
+		}
+	}
+	if n := atomic.LoadInt32(&server.requests); n != 3 {
+		t.Errorf("got %d requests, want 3", n)
+	}
+}
+
+func TestFlightConcurrent(t *testing.T) {
+	server := newBlockingServer()
+	close(server.release)
+	server.started = make(chan struct{}, 100)
+	client := server.client()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Sentiment(SentimentParams{Text: "I love it"}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+}
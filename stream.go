@@ -0,0 +1,143 @@
+package nlpcloud
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// streamDelimiter separates the chunks sent by the API while streaming.
+const streamDelimiter = '\x00'
+
+// streamEndMarker is the chunk sent by the API at the end of a stream.
+const streamEndMarker = "[DONE]"
+
+// Stream reads the chunks of text returned by StreamingGeneration and
+// StreamingChatbot.
+//
+//	stream := nlpcloud.NewStream(streamBody)
+//	defer stream.Close()
+//	for stream.Next() {
+//		fmt.Print(stream.Text())
+//	}
+//	if err := stream.Err(); err != nil { ... }
+//
+// A Stream is not safe for concurrent use.
+type Stream struct {
+	body   io.ReadCloser
+	reader *bufio.Reader
+	text   string
+	err    error
+	done   bool
+}
+
+// NewStream initializes a new Stream reading from the body returned by a
+// streaming endpoint.
+func NewStream(body io.ReadCloser) *Stream {
+	return &Stream{
+		body:   body,
+		reader: bufio.NewReader(body),
+	}
+}
+
+// Next advances to the next chunk of text, which is then available
+// through Text. It returns false at the end of the stream or on error.
+// Bytes are buffered until a full chunk is received, so multi-byte UTF-8
+// characters split across reads are kept intact.
+func (s *Stream) Next() bool {
+	s.text = ""
+	for !s.done {
+		chunk, err := s.reader.ReadBytes(streamDelimiter)
+		if err != nil {
+			s.done = true
+			if !errors.Is(err, io.EOF) {
+				s.err = err
+				return false
+			}
+		}
+		chunk = bytes.TrimSuffix(chunk, []byte{streamDelimiter})
+		if strings.TrimSpace(string(chunk)) == streamEndMarker {
+			s.done = true
+			return false
+		}
+		if len(chunk) == 0 {
+			continue
+		}
+		if !utf8.Valid(chunk) {
+			chunk = bytes.ToValidUTF8(chunk, []byte(string(utf8.RuneError)))
+		}
+		s.text = string(chunk)
+		return true
+	}
+	return false
+}
+
+// Text returns the current chunk of text.
+func (s *Stream) Text() string {
+	return s.text
+}
+
+// Err returns the first error met while reading the stream, if any.
+func (s *Stream) Err() error {
+	return s.err
+}
+
+// Close closes the underlying body. Next returns false afterwards.
+func (s *Stream) Close() error {
+	s.done = true
+	return s.body.Close()
+}
+
+// Collect reads the remaining chunks and returns their concatenation.
+func (s *Stream) Collect() (string, error) {
+	var sb strings.Builder
+	for s.Next() {
+		sb.WriteString(s.text)
+	}
+	return sb.String(), s.err
+}
+
+// WriteTo writes the remaining chunks to w as they are received.
+func (s *Stream) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for s.Next() {
+		n, err := io.WriteString(w, s.text)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, s.err
+}
+
+// Chunks delivers the remaining chunks on the returned channel, which is
+// closed at the end of the stream, on error, or when ctx is done.
+// Err must only be called once the channel is closed.
+func (s *Stream) Chunks(ctx context.Context) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for s.Next() {
+			// Chunks are not delivered once ctx is done, even if a receiver
+			// is ready
+			if err := ctx.Err(); err != nil {
+				s.err = err
+				return
+			}
+			select {
+			case ch <- s.text:
+			case <-ctx.Done():
+				s.err = ctx.Err()
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// Makes sure the *Stream works with io.WriterTo.
+var _ io.WriterTo = (*Stream)(nil)
@@ -0,0 +1,108 @@
+package nlpcloud_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// chunksOf reads all the chunks of a stream body, one byte at a time.
+func chunksOf(t *testing.T, body string) []string {
+	t.Helper()
+	stream := nlpcloud.NewStream(io.NopCloser(iotest.OneByteReader(strings.NewReader(body))))
+	var chunks []string
+	for stream.Next() {
+		chunks = append(chunks, stream.Text())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		chunks []string
+	}{
+		{name: "multi-byte characters", body: "héllo\x00 wörld 日本語\x00 🎉\x00", chunks: []string{"héllo", " wörld 日本語", " 🎉"}},
+		{name: "end marker", body: "日本\x00語\x00[DONE]", chunks: []string{"日本", "語"}},
+		{name: "delimited end marker", body: "日本\x00語\x00[DONE]\x00", chunks: []string{"日本", "語"}},
+		{name: "chunks after end marker", body: "a\x00[DONE]\x00b\x00", chunks: []string{"a"}},
+		{name: "no delimiter", body: "Ça va ?", chunks: []string{"Ça va ?"}},
+		{name: "empty chunks", body: "\x00a\x00\x00b", chunks: []string{"a", "b"}},
+		{name: "invalid UTF-8", body: "a\xffb\x00", chunks: []string{"a�b"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks := chunksOf(t, test.body)
+			if strings.Join(chunks, "|") != strings.Join(test.chunks, "|") || len(chunks) != len(test.chunks) {
+				t.Errorf("got chunks %q, want %q", chunks, test.chunks)
+			}
+		})
+	}
+}
+
+func TestStreamCollect(t *testing.T) {
+	body := io.NopCloser(iotest.OneByteReader(strings.NewReader("Bonjour, ça\x00 va ? 👋\x00[DONE]")))
+	text, err := nlpcloud.NewStream(body).Collect()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "Bonjour, ça va ? 👋" {
+		t.Errorf("got text %q", text)
+	}
+}
+
+func TestStreamReadError(t *testing.T) {
+	errRead := errors.New("connection reset")
+	body := io.NopCloser(io.MultiReader(strings.NewReader("a\x00b"), iotest.ErrReader(errRead)))
+	stream := nlpcloud.NewStream(body)
+	if !stream.Next() || stream.Text() != "a" {
+		t.Fatalf("got chunk %q, want %q", stream.Text(), "a")
+	}
+	if stream.Next() {
+		t.Errorf("got chunk %q after the error", stream.Text())
+	}
+	if err := stream.Err(); !errors.Is(err, errRead) {
+		t.Errorf("got error %v, want %v", err, errRead)
+	}
+}
+
+func TestStreamChunksCancel(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	stream := nlpcloud.NewStream(reader)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks := stream.Chunks(ctx)
+	go io.WriteString(writer, "é\x00")
+	if chunk := <-chunks; chunk != "é" {
+		t.Fatalf("got chunk %q, want %q", chunk, "é")
+	}
+
+	// The chunk received once ctx is done is not delivered
+	cancel()
+	if _, err := io.WriteString(writer, "ü\x00"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case chunk, ok := <-chunks:
+		if ok {
+			t.Errorf("got chunk %q after cancellation", chunk)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the channel was not closed")
+	}
+	if err := stream.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,128 @@
+package nlpcloud
+
+import (
+	"errors"
+	"strings"
+)
+
+// SummarizeLongParams wraps all the parameters for SummarizeLong.
+type SummarizeLongParams struct {
+	Text string
+	// ChunkTokens is the maximum number of tokens of a chunk, which must
+	// fit in the model context. Default is 800.
+	ChunkTokens int
+	// Sizes holds the summary size ("small" or "large") of each stage, the
+	// last one being used for the next stages. Default is the model
+	// default.
+	Sizes []string
+	// MaxStages is the maximum number of stages. Default is 5.
+	MaxStages int
+	// CountTokens counts the tokens of a text. Default is EstimateTokens.
+	CountTokens func(text string) int
+	// Chunk defines how the chunks are batched.
+	Chunk ChunkParams
+	// OnStage is called with the results of each stage.
+	OnStage func(stage SummarizeLongStage)
+}
+
+// SummarizeLongStage holds the results of a stage of SummarizeLong.
+type SummarizeLongStage struct {
+	// Stage is the stage number, starting from 1.
+	Stage     int
+	Size      string
+	Chunks    []string
+	Summaries []string
+}
+
+// LongSummarization holds the summary of a long text.
+type LongSummarization struct {
+	SummaryText string
+	// Stages is the number of stages needed.
+	Stages int
+}
+
+// SummarizeLong summarizes a text longer than the model context. The text
+// is split on paragraph and sentence boundaries into chunks, summarized
+// with batch summarizations, and the joined summaries are summarized again
+// until they fit in a chunk.
+func (c *Client) SummarizeLong(params SummarizeLongParams, opts ...Option) (*LongSummarization, error) {
+	if params.ChunkTokens < 1 {
+		params.ChunkTokens = 800
+	}
+	if params.MaxStages < 1 {
+		params.MaxStages = 5
+	}
+	if params.CountTokens == nil {
+		params.CountTokens = EstimateTokens
+	}
+
+	text := params.Text
+	for stage := 1; ; stage++ {
+		size := ""
+		if n := len(params.Sizes); n > 0 {
+			if stage <= n {
+				size = params.Sizes[stage-1]
+			} else {
+				size = params.Sizes[n-1]
+			}
+		}
+
+		tokens := params.CountTokens(text)
+		if tokens <= params.ChunkTokens {
+			summaryParams := SummarizationParams{Text: text}
+			if size != "" {
+				summaryParams.Size = &size
+			}
+			summarization, err := c.Summarization(summaryParams, opts...)
+			if err != nil {
+				return nil, err
+			}
+			if params.OnStage != nil {
+				params.OnStage(SummarizeLongStage{Stage: stage, Size: size, Chunks: []string{text}, Summaries: []string{summarization.SummaryText}})
+			}
+			return &LongSummarization{SummaryText: summarization.SummaryText, Stages: stage}, nil
+		}
+		if stage == params.MaxStages {
+			return nil, errors.New("the text does not fit in a chunk after the maximum number of stages")
+		}
+
+		segments := chunkText(text, params.ChunkTokens, params.CountTokens)
+		chunks := make([]string, len(segments))
+		for i, seg := range segments {
+			chunks[i] = text[seg.start:seg.end]
+		}
+		summaries, err := c.summarizeChunks(chunks, size, params.Chunk, opts)
+		if err != nil {
+			return nil, err
+		}
+		if params.OnStage != nil {
+			params.OnStage(SummarizeLongStage{Stage: stage, Size: size, Chunks: chunks, Summaries: summaries})
+		}
+
+		text = strings.Join(summaries, "\n\n")
+		if params.CountTokens(text) >= tokens {
+			return nil, errors.New("the summaries are not shorter than the text")
+		}
+	}
+}
+
+// summarizeChunks summarizes chunks with batch summarizations, falling back
+// to single summarizations for the failed chunks.
+func (c *Client) summarizeChunks(chunks []string, size string, chunk ChunkParams, opts []Option) ([]string, error) {
+	batch, err := c.ChunkedBatchSummarization(BatchSummarizationParams{Texts: chunks, Size: size}, chunk, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, failure := range batch.Failures {
+		summaryParams := SummarizationParams{Text: chunks[failure.Index]}
+		if size != "" {
+			summaryParams.Size = &size
+		}
+		summarization, err := c.Summarization(summaryParams, opts...)
+		if err != nil {
+			return nil, err
+		}
+		batch.SummaryTexts[failure.Index] = summarization.SummaryText
+	}
+	return batch.SummaryTexts, nil
+}
@@ -0,0 +1,207 @@
+package nlpcloud
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// segment is a span of a text, without its surrounding spaces.
+type segment struct {
+	start, end int
+}
+
+// trimSegment returns the span [start, end) of text without its
+// surrounding spaces, and false if it is blank.
+func trimSegment(text string, start, end int) (segment, bool) {
+	for start < end {
+		r, size := utf8.DecodeRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		r, size := utf8.DecodeLastRuneInString(text[start:end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		end -= size
+	}
+	return segment{start: start, end: end}, start < end
+}
+
+// splitParagraphs splits text on blank lines.
+func splitParagraphs(text string) []segment {
+	var segments []segment
+	start := 0
+	for i := 0; i < len(text); {
+		if text[i] != '\n' {
+			i++
+			continue
+		}
+		// Look for a second line break separated by spaces only
+		j := i + 1
+		for j < len(text) && (text[j] == ' ' || text[j] == '\t' || text[j] == '\r') {
+			j++
+		}
+		if j < len(text) && text[j] == '\n' {
+			if seg, ok := trimSegment(text, start, i); ok {
+				segments = append(segments, seg)
+			}
+			for j < len(text) && unicode.IsSpace(rune(text[j])) {
+				j++
+			}
+			start = j
+		}
+		i = j
+	}
+	if seg, ok := trimSegment(text, start, len(text)); ok {
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// splitSentences splits the span of text into sentences. A sentence ends
+// with a terminal punctuation followed by a space, unless the next word is
+// in lower case (e.g. after an abbreviation).
+func splitSentences(text string, span segment) []segment {
+	var segments []segment
+	start := span.start
+	for i := span.start; i < span.end; {
+		r, size := utf8.DecodeRuneInString(text[i:span.end])
+		i += size
+		if !isTerminal(r) {
+			continue
+		}
+		// Include the closing punctuation
+		for i < span.end {
+			r, size := utf8.DecodeRuneInString(text[i:span.end])
+			if !isTerminal(r) && !unicode.Is(unicode.Pe, r) && !unicode.Is(unicode.Pf, r) && r != '"' && r != '\'' {
+				break
+			}
+			i += size
+		}
+		end := i
+		if r < utf8.RuneSelf {
+			// Latin terminals need a following space
+			next, _ := utf8.DecodeRuneInString(text[i:span.end])
+			if i < span.end && !unicode.IsSpace(next) {
+				continue
+			}
+			j := i
+			for j < span.end {
+				r, size := utf8.DecodeRuneInString(text[j:span.end])
+				if !unicode.IsSpace(r) {
+					break
+				}
+				j += size
+			}
+			if next, _ := utf8.DecodeRuneInString(text[j:span.end]); j < span.end && unicode.IsLower(next) {
+				continue
+			}
+		}
+		if seg, ok := trimSegment(text, start, end); ok {
+			segments = append(segments, seg)
+		}
+		start = end
+	}
+	if seg, ok := trimSegment(text, start, span.end); ok {
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+func isTerminal(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？', '…':
+		return true
+	}
+	return false
+}
+
+// splitWords splits the span of text on spaces.
+func splitWords(text string, span segment) []segment {
+	var segments []segment
+	start := -1
+	for i := span.start; i < span.end; {
+		r, size := utf8.DecodeRuneInString(text[i:span.end])
+		if unicode.IsSpace(r) {
+			if start >= 0 {
+				segments = append(segments, segment{start: start, end: i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+		i += size
+	}
+	if start >= 0 {
+		segments = append(segments, segment{start: start, end: span.end})
+	}
+	return segments
+}
+
+// EstimateTokens estimates the number of tokens of a text, counting about
+// 4 characters per token.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// chunkText splits text into chunks of at most maxTokens tokens, counted
+// with count. Chunks end on paragraph boundaries when possible, then on
+// sentence boundaries, then on spaces. A word longer than maxTokens makes a
+// chunk on its own.
+func chunkText(text string, maxTokens int, count func(string) int) []segment {
+	var units []segment
+	for _, paragraph := range splitParagraphs(text) {
+		if count(text[paragraph.start:paragraph.end]) <= maxTokens {
+			units = append(units, paragraph)
+			continue
+		}
+		for _, sentence := range splitSentences(text, paragraph) {
+			if count(text[sentence.start:sentence.end]) <= maxTokens {
+				units = append(units, sentence)
+				continue
+			}
+			units = append(units, packSegments(text, splitWords(text, sentence), maxTokens, count)...)
+		}
+	}
+	return packSegments(text, units, maxTokens, count)
+}
+
+// packSegments merges consecutive segments into chunks of at most maxTokens
+// tokens.
+func packSegments(text string, segments []segment, maxTokens int, count func(string) int) []segment {
+	var chunks []segment
+	for _, seg := range segments {
+		if n := len(chunks); n > 0 && count(text[chunks[n-1].start:seg.end]) <= maxTokens {
+			chunks[n-1].end = seg.end
+			continue
+		}
+		chunks = append(chunks, seg)
+	}
+	return chunks
+}
+
+// TextChunk is a chunk of a text returned by SplitText.
+type TextChunk struct {
+	Text string
+	// Start and End are the byte offsets of the chunk in the text.
+	Start int
+	End   int
+}
+
+// SplitText splits text into chunks of at most maxTokens tokens, counted
+// with countTokens, or EstimateTokens if nil. Chunks end on paragraph
+// boundaries when possible, then on sentence boundaries, then on spaces.
+func SplitText(text string, maxTokens int, countTokens func(text string) int) []TextChunk {
+	if countTokens == nil {
+		countTokens = EstimateTokens
+	}
+	segments := chunkText(text, maxTokens, countTokens)
+	chunks := make([]TextChunk, len(segments))
+	for i, seg := range segments {
+		chunks[i] = TextChunk{Text: text[seg.start:seg.end], Start: seg.start, End: seg.end}
+	}
+	return chunks
+}
@@ -0,0 +1,265 @@
+package nlpcloud
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TranslateDocumentParams wraps all the parameters for TranslateDocument.
+type TranslateDocumentParams struct {
+	Text string
+	// Source is the language of the text. Default is detected by the
+	// model, if supported.
+	Source string
+	Target string
+	// SegmentTokens is the maximum number of tokens of a translated
+	// segment. Longer lines are split on sentence boundaries.
+	// Default is 400.
+	SegmentTokens int
+	// CountTokens counts the tokens of a text. Default is EstimateTokens.
+	CountTokens func(text string) int
+	// Chunk defines how the segments are batched.
+	Chunk ChunkParams
+}
+
+// DocumentTranslation holds the translation of a document.
+type DocumentTranslation struct {
+	TranslationText string
+}
+
+var (
+	// listMarker matches the indentation and the marker of a list item,
+	// heading or quote.
+	listMarker = regexp.MustCompile(`^[ \t]*(?:[-*+•]|\d+[.)]|#{1,6}|>)[ \t]+`)
+	// placeholder matches the inline code spans, URLs and {{variables}}
+	// that must not be translated.
+	placeholder = regexp.MustCompile("`[^`\n]+`|https?://[^\\s<>\"'`]*[^\\s<>\"'`.,;:!?)\\]]|\\{\\{[^{}\n]*\\}\\}")
+	// placeholderRef matches a placeholder once replaced in a segment.
+	placeholderRef = regexp.MustCompile(`\{\{\s*(\d+)\s*\}\}`)
+)
+
+// linePiece is a part of a line of a document: text to translate, or text
+// kept as is.
+type linePiece struct {
+	text      string
+	translate bool
+	// segments holds the segments the text is translated in.
+	segments []pieceSegment
+}
+
+// pieceSegment is a segment of a linePiece, with its placeholders replaced.
+type pieceSegment struct {
+	// index is the index of the translated segment, or -1 if text is kept
+	// as is.
+	index        int
+	text         string
+	placeholders []string
+}
+
+// TranslateDocument translates a document of any length, preserving its
+// structure. Each line is translated on its own, longer ones being split on
+// sentence boundaries, and the segments are translated with batch
+// translations.
+//
+// Line breaks are kept, so tables, addresses and verse keep their layout.
+// Blank lines, indentation, list markers, table pipes and fenced code
+// blocks are kept as is, as well as inline code spans, URLs and
+// {{variables}}.
+func (c *Client) TranslateDocument(params TranslateDocumentParams, opts ...Option) (*DocumentTranslation, error) {
+	if params.SegmentTokens < 1 {
+		params.SegmentTokens = 400
+	}
+	if params.CountTokens == nil {
+		params.CountTokens = EstimateTokens
+	}
+	newline := "\n"
+	if strings.Contains(params.Text, "\r\n") {
+		newline = "\r\n"
+	}
+	lines := parseDocument(strings.Split(params.Text, newline))
+
+	// Collect the segments to translate, with their placeholders replaced
+	var segments []string
+	for _, line := range lines {
+		for i := range line {
+			piece := &line[i]
+			if !piece.translate {
+				continue
+			}
+			for _, seg := range chunkText(piece.text, params.SegmentTokens, params.CountTokens) {
+				text := piece.text[seg.start:seg.end]
+				masked, placeholders := maskPlaceholders(text)
+				s := pieceSegment{index: -1, text: text, placeholders: placeholders}
+				if hasLetters(placeholderRef.ReplaceAllString(masked, "")) {
+					s.index = len(segments)
+					segments = append(segments, masked)
+				}
+				piece.segments = append(piece.segments, s)
+			}
+		}
+	}
+
+	translations, err := c.translateSegments(segments, params, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	translated := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		for _, piece := range line {
+			if !piece.translate {
+				b.WriteString(piece.text)
+				continue
+			}
+			for j, s := range piece.segments {
+				if j > 0 {
+					b.WriteString(" ")
+				}
+				if s.index < 0 {
+					b.WriteString(s.text)
+					continue
+				}
+				b.WriteString(unmaskPlaceholders(translations[s.index], s.placeholders))
+			}
+		}
+		translated[i] = b.String()
+	}
+	return &DocumentTranslation{TranslationText: strings.Join(translated, newline)}, nil
+}
+
+// translateSegments translates segments with batch translations, falling
+// back to single translations for the failed segments.
+func (c *Client) translateSegments(segments []string, params TranslateDocumentParams, opts []Option) ([]string, error) {
+	if len(segments) == 0 {
+		return nil, nil
+	}
+	batchParams := BatchTranslationParams{Texts: segments}
+	var source, target *string
+	if params.Source != "" {
+		sources := make([]string, len(segments))
+		for i := range sources {
+			sources[i] = params.Source
+		}
+		batchParams.Sources = &sources
+		source = &params.Source
+	}
+	if params.Target != "" {
+		targets := make([]string, len(segments))
+		for i := range targets {
+			targets[i] = params.Target
+		}
+		batchParams.Targets = &targets
+		target = &params.Target
+	}
+
+	batch, err := c.ChunkedBatchTranslation(batchParams, params.Chunk, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for _, failure := range batch.Failures {
+		translation, err := c.Translation(TranslationParams{Text: segments[failure.Index], Source: source, Target: target}, opts...)
+		if err != nil {
+			return nil, err
+		}
+		batch.TranslationTexts[failure.Index] = translation.TranslationText
+	}
+	return batch.TranslationTexts, nil
+}
+
+// parseDocument splits each line of a document into pieces to translate
+// and pieces kept as is.
+func parseDocument(lines []string) [][]linePiece {
+	parsed := make([][]linePiece, len(lines))
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		fence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		switch {
+		case inFence || fence || trimmed == "":
+			if fence {
+				inFence = !inFence
+			}
+			parsed[i] = []linePiece{{text: line}}
+		case strings.HasPrefix(trimmed, "|"):
+			parsed[i] = parseTableRow(line)
+		default:
+			prefix := listMarker.FindString(line)
+			if prefix == "" {
+				prefix = line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+			}
+			parsed[i] = appendTrimmed([]linePiece{{text: prefix}}, line[len(prefix):])
+		}
+	}
+	return parsed
+}
+
+// parseTableRow splits a table row into its cells, keeping the pipes and
+// the padding of the cells as is.
+func parseTableRow(line string) []linePiece {
+	var pieces []linePiece
+	start := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			// Skip the escaped character, e.g. "\|"
+			i++
+		case '|':
+			pieces = appendTrimmed(pieces, line[start:i])
+			pieces = append(pieces, linePiece{text: "|"})
+			start = i + 1
+		}
+	}
+	return appendTrimmed(pieces, line[start:])
+}
+
+// appendTrimmed appends text to pieces, translating it without its
+// surrounding spaces.
+func appendTrimmed(pieces []linePiece, text string) []linePiece {
+	seg, ok := trimSegment(text, 0, len(text))
+	if !ok {
+		return append(pieces, linePiece{text: text})
+	}
+	return append(pieces,
+		linePiece{text: text[:seg.start]},
+		linePiece{text: text[seg.start:seg.end], translate: true},
+		linePiece{text: text[seg.end:]},
+	)
+}
+
+// maskPlaceholders replaces the code spans, URLs and {{variables}} of text
+// with numbered {{n}} placeholders.
+func maskPlaceholders(text string) (string, []string) {
+	var placeholders []string
+	masked := placeholder.ReplaceAllStringFunc(text, func(match string) string {
+		placeholders = append(placeholders, match)
+		return "{{" + strconv.Itoa(len(placeholders)-1) + "}}"
+	})
+	return masked, placeholders
+}
+
+// unmaskPlaceholders restores the placeholders of a translated text. The
+// placeholders dropped by the translation are appended to the text.
+func unmaskPlaceholders(text string, placeholders []string) string {
+	restored := make([]bool, len(placeholders))
+	text = placeholderRef.ReplaceAllStringFunc(text, func(match string) string {
+		i, err := strconv.Atoi(placeholderRef.FindStringSubmatch(match)[1])
+		if err != nil || i >= len(placeholders) {
+			return match
+		}
+		restored[i] = true
+		return placeholders[i]
+	})
+	for i, ok := range restored {
+		if !ok {
+			text += " " + placeholders[i]
+		}
+	}
+	return text
+}
+
+func hasLetters(text string) bool {
+	return strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
@@ -0,0 +1,156 @@
+package nlpcloud_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/nlpcloud/nlpcloud-go"
+	"github.com/nlpcloud/nlpcloud-go/nlpcloudtest"
+)
+
+// translateWith programs the batch translations of server to translate
+// each text with fn.
+func translateWith(server *nlpcloudtest.Server, fn func(string) string) {
+	server.Handle("batch-translation", func(req nlpcloudtest.RecordedRequest) nlpcloudtest.Response {
+		var params nlpcloud.BatchTranslationParams
+		if err := req.Decode(&params); err != nil {
+			return nlpcloudtest.Error(http.StatusBadRequest, err.Error())
+		}
+		translations := make([]string, len(params.Texts))
+		for i, text := range params.Texts {
+			translations[i] = fn(text)
+		}
+		return nlpcloudtest.JSON(http.StatusOK, nlpcloud.BatchTranslation{TranslationTexts: translations})
+	})
+}
+
+// sentTexts returns the texts sent to the batch translations of server.
+func sentTexts(t *testing.T, server *nlpcloudtest.Server) []string {
+	t.Helper()
+	var texts []string
+	for _, req := range server.RequestsFor("batch-translation") {
+		var params nlpcloud.BatchTranslationParams
+		if err := req.Decode(&params); err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, params.Texts...)
+	}
+	return texts
+}
+
+func TestTranslateDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		want     string
+		segments []string
+	}{
+		{
+			name:     "address",
+			text:     "John Smith\n12 Main Street\nSpringfield",
+			want:     "JOHN SMITH\n12 MAIN STREET\nSPRINGFIELD",
+			segments: []string{"John Smith", "12 Main Street", "Springfield"},
+		},
+		{
+			name:     "verse and blank lines",
+			text:     "Roses are red\nViolets are blue\n\n  \nSugar is sweet\n",
+			want:     "ROSES ARE RED\nVIOLETS ARE BLUE\n\n  \nSUGAR IS SWEET\n",
+			segments: []string{"Roses are red", "Violets are blue", "Sugar is sweet"},
+		},
+		{
+			name:     "list markers",
+			text:     "# Title\n- first item\n  * nested item\n1. numbered item\n> quote\n    indented",
+			want:     "# TITLE\n- FIRST ITEM\n  * NESTED ITEM\n1. NUMBERED ITEM\n> QUOTE\n    INDENTED",
+			segments: []string{"Title", "first item", "nested item", "numbered item", "quote", "indented"},
+		},
+		{
+			name:     "table",
+			text:     "| Name | City  |\n|------|:-----:|\n| John | Paris |\n|  a \\| b |  |",
+			want:     "| NAME | CITY  |\n|------|:-----:|\n| JOHN | PARIS |\n|  A \\| B |  |",
+			segments: []string{"Name", "City", "John", "Paris", "a \\| b"},
+		},
+		{
+			name:     "placeholders",
+			text:     "Run `go test` on https://example.com/path?q=a and greet {{name}}.",
+			want:     "RUN `go test` ON https://example.com/path?q=a AND GREET {{name}}.",
+			segments: []string{"Run {{0}} on {{1}} and greet {{2}}."},
+		},
+		{
+			name:     "placeholders only",
+			text:     "`make build`\nhttps://example.com",
+			want:     "`make build`\nhttps://example.com",
+			segments: nil,
+		},
+		{
+			name:     "fenced code",
+			text:     "Build it:\n```go\nfunc main() {}\n```\nDone",
+			want:     "BUILD IT:\n```go\nfunc main() {}\n```\nDONE",
+			segments: []string{"Build it:", "Done"},
+		},
+		{
+			name:     "crlf",
+			text:     "Hello\r\nWorld\r\n",
+			want:     "HELLO\r\nWORLD\r\n",
+			segments: []string{"Hello", "World"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := nlpcloudtest.NewServer("token")
+			defer server.Close()
+			client := server.NewClient(nlpcloud.ClientParams{Model: "nllb-200-3-3b"})
+			translateWith(server, strings.ToUpper)
+
+			translation, err := client.TranslateDocument(nlpcloud.TranslateDocumentParams{Text: test.text, Source: "eng_Latn", Target: "fra_Latn"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if translation.TranslationText != test.want {
+				t.Errorf("got translation %q, want %q", translation.TranslationText, test.want)
+			}
+			if got := sentTexts(t, server); strings.Join(got, "\x00") != strings.Join(test.segments, "\x00") {
+				t.Errorf("got segments %q, want %q", got, test.segments)
+			}
+		})
+	}
+}
+
+func TestTranslateDocumentLongLine(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "nllb-200-3-3b"})
+	translateWith(server, strings.ToUpper)
+
+	translation, err := client.TranslateDocument(nlpcloud.TranslateDocumentParams{
+		Text:          "- The first sentence is here. The second one follows.\nEnd",
+		Target:        "fra_Latn",
+		SegmentTokens: 8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "- THE FIRST SENTENCE IS HERE. THE SECOND ONE FOLLOWS.\nEND"; translation.TranslationText != want {
+		t.Errorf("got translation %q, want %q", translation.TranslationText, want)
+	}
+	if got := sentTexts(t, server); len(got) != 3 || got[0] != "The first sentence is here." {
+		t.Errorf("got segments %q", got)
+	}
+}
+
+func TestTranslateDocumentDroppedPlaceholder(t *testing.T) {
+	server := nlpcloudtest.NewServer("token")
+	defer server.Close()
+	client := server.NewClient(nlpcloud.ClientParams{Model: "nllb-200-3-3b"})
+	translateWith(server, func(text string) string {
+		return strings.ToUpper(strings.Replace(text, " {{0}}", "", 1))
+	})
+
+	translation, err := client.TranslateDocument(nlpcloud.TranslateDocumentParams{Text: "See https://example.com for details", Target: "fra_Latn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "SEE FOR DETAILS https://example.com"; translation.TranslationText != want {
+		t.Errorf("got translation %q, want %q", translation.TranslationText, want)
+	}
+}
@@ -0,0 +1,333 @@
+// Package vectorindex provides an in-process vector index over the
+// embeddings returned by the nlpcloud Client, with exact and approximate
+// (IVF) top-k search, metadata filters, and persistence to disk.
+//
+//	index := vectorindex.New(vectorindex.Params{Metric: vectorindex.Cosine})
+//	err := index.Ingest(client, documents, nlpcloud.ChunkParams{})
+//	results, err := index.SearchText(client, "query", 10, nil)
+//
+// An Index is safe for concurrent use.
+package vectorindex
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Metric is the similarity metric of an Index.
+type Metric int
+
+const (
+	// Cosine ranks the vectors by cosine similarity.
+	Cosine Metric = iota
+	// Dot ranks the vectors by dot product.
+	Dot
+	// Euclidean ranks the vectors by euclidean distance.
+	Euclidean
+)
+
+// Item is a vector stored in an Index.
+type Item struct {
+	// ID identifies the item. Adding an item with an existing ID replaces
+	// it.
+	ID       string
+	Vector   []float64
+	Text     string
+	Metadata map[string]interface{}
+}
+
+// Result is an item returned by a search.
+type Result struct {
+	ID       string
+	Text     string
+	Metadata map[string]interface{}
+	// Score is the similarity for Cosine and Dot, and the distance for
+	// Euclidean. Results are sorted from the best score.
+	Score float64
+}
+
+// Filter selects the items a search may return out of their metadata.
+type Filter func(metadata map[string]interface{}) bool
+
+// Equals returns a Filter selecting the items whose metadata key holds
+// value. Values are compared through their JSON encoding, so numbers match
+// whatever their type.
+func Equals(key string, value interface{}) Filter {
+	want, _ := json.Marshal(value)
+	return func(metadata map[string]interface{}) bool {
+		got, ok := metadata[key]
+		if !ok {
+			return false
+		}
+		j, _ := json.Marshal(got)
+		return string(j) == string(want)
+	}
+}
+
+// Params wraps all the parameters for the Index initialization.
+type Params struct {
+	// Metric is the similarity metric. Default is Cosine.
+	Metric Metric
+}
+
+// Index stores vectors with their IDs and metadata.
+type Index struct {
+	mu       sync.RWMutex
+	metric   Metric
+	dim      int
+	ids      []string
+	texts    []string
+	metadata []map[string]interface{}
+	// vectors holds the vectors, normalized for Cosine.
+	vectors [][]float32
+	byID    map[string]int
+	ivf     *ivf
+}
+
+// ErrDimension is returned when a vector does not have the dimension of
+// the index.
+var ErrDimension = errors.New("vectorindex: vector dimension mismatch")
+
+// New initializes a new empty Index.
+func New(params Params) *Index {
+	return &Index{
+		metric: params.Metric,
+		byID:   map[string]int{},
+	}
+}
+
+// Len returns the number of items in the index.
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.ids)
+}
+
+// Add adds items to the index. All the vectors must have the same
+// dimension.
+func (x *Index) Add(items ...Item) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, item := range items {
+		if x.dim == 0 {
+			x.dim = len(item.Vector)
+		}
+		if len(item.Vector) != x.dim || x.dim == 0 {
+			return fmt.Errorf("%w: got %d, want %d", ErrDimension, len(item.Vector), x.dim)
+		}
+		vector := x.prepare(item.Vector)
+		i, ok := x.byID[item.ID]
+		if !ok {
+			i = len(x.ids)
+			x.byID[item.ID] = i
+			x.ids = append(x.ids, item.ID)
+			x.texts = append(x.texts, item.Text)
+			x.metadata = append(x.metadata, item.Metadata)
+			x.vectors = append(x.vectors, vector)
+			if x.ivf != nil {
+				x.ivf.add(i, vector)
+			}
+			continue
+		}
+		x.texts[i] = item.Text
+		x.metadata[i] = item.Metadata
+		x.vectors[i] = vector
+		if x.ivf != nil {
+			x.ivf.remove(i)
+			x.ivf.add(i, vector)
+		}
+	}
+	return nil
+}
+
+// Delete removes the item id, and reports whether it existed.
+func (x *Index) Delete(id string) bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	i, ok := x.byID[id]
+	if !ok {
+		return false
+	}
+	x.remove(i)
+	return true
+}
+
+// DeleteMatching removes the items selected by filter, and returns their
+// number.
+func (x *Index) DeleteMatching(filter Filter) int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	deleted := 0
+	// The last item is moved to the removed one, so iterate backwards
+	for i := len(x.ids) - 1; i >= 0; i-- {
+		if filter(x.metadata[i]) {
+			x.remove(i)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// remove removes the item i, moving the last item to its place. x.mu must
+// be held.
+func (x *Index) remove(i int) {
+	id := x.ids[i]
+	last := len(x.ids) - 1
+	if x.ivf != nil {
+		x.ivf.remove(i)
+		if i != last {
+			x.ivf.move(last, i)
+		}
+	}
+	x.ids[i], x.texts[i], x.metadata[i], x.vectors[i] = x.ids[last], x.texts[last], x.metadata[last], x.vectors[last]
+	x.byID[x.ids[i]] = i
+	x.ids, x.texts, x.metadata, x.vectors = x.ids[:last], x.texts[:last], x.metadata[:last], x.vectors[:last]
+	delete(x.byID, id)
+}
+
+// Get returns the item id, if any.
+func (x *Index) Get(id string) (Item, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	i, ok := x.byID[id]
+	if !ok {
+		return Item{}, false
+	}
+	vector := make([]float64, len(x.vectors[i]))
+	for j, v := range x.vectors[i] {
+		vector[j] = float64(v)
+	}
+	return Item{ID: id, Vector: vector, Text: x.texts[i], Metadata: x.metadata[i]}, true
+}
+
+// Search returns the k items closest to vector and selected by filter,
+// which may be nil. The approximate index is used if it was built with
+// BuildIVF.
+func (x *Index) Search(vector []float64, k int, filter Filter) ([]Result, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.ids) == 0 || k < 1 {
+		return nil, nil
+	}
+	if len(vector) != x.dim {
+		return nil, fmt.Errorf("%w: got %d, want %d", ErrDimension, len(vector), x.dim)
+	}
+	query := x.prepare(vector)
+
+	top := &topK{k: k}
+	consider := func(i int) {
+		if filter != nil && !filter(x.metadata[i]) {
+			return
+		}
+		top.push(candidate{index: i, score: x.score(query, x.vectors[i])})
+	}
+	if x.ivf == nil {
+		for i := range x.vectors {
+			consider(i)
+		}
+	} else {
+		for _, list := range x.ivf.probe(query) {
+			for _, i := range list {
+				consider(i)
+			}
+		}
+	}
+
+	candidates := top.sorted()
+	results := make([]Result, len(candidates))
+	for j, c := range candidates {
+		score := c.score
+		if x.metric == Euclidean {
+			score = math.Sqrt(-score)
+		}
+		results[j] = Result{ID: x.ids[c.index], Text: x.texts[c.index], Metadata: x.metadata[c.index], Score: score}
+	}
+	return results, nil
+}
+
+// prepare converts a vector to float32, normalizing it for Cosine.
+func (x *Index) prepare(vector []float64) []float32 {
+	norm := 1.0
+	if x.metric == Cosine {
+		sum := 0.0
+		for _, v := range vector {
+			sum += v * v
+		}
+		if sum > 0 {
+			norm = math.Sqrt(sum)
+		}
+	}
+	prepared := make([]float32, len(vector))
+	for i, v := range vector {
+		prepared[i] = float32(v / norm)
+	}
+	return prepared
+}
+
+// score returns the similarity of two prepared vectors, higher being
+// closer. It is the negated squared distance for Euclidean.
+func (x *Index) score(a, b []float32) float64 {
+	if x.metric == Euclidean {
+		return -squaredDistance(a, b)
+	}
+	return dot(a, b)
+}
+
+func dot(a, b []float32) float64 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return float64(sum)
+}
+
+func squaredDistance(a, b []float32) float64 {
+	var sum float32
+	for i := range a {
+		d := a[i] - b[i]
+		sum += d * d
+	}
+	return float64(sum)
+}
+
+type candidate struct {
+	index int
+	score float64
+}
+
+// topK keeps the k candidates with the best scores, in a min-heap.
+type topK struct {
+	k     int
+	items []candidate
+}
+
+func (t *topK) Len() int           { return len(t.items) }
+func (t *topK) Less(i, j int) bool { return t.items[i].score < t.items[j].score }
+func (t *topK) Swap(i, j int)      { t.items[i], t.items[j] = t.items[j], t.items[i] }
+func (t *topK) Push(x interface{}) { t.items = append(t.items, x.(candidate)) }
+func (t *topK) Pop() interface{} {
+	last := t.items[len(t.items)-1]
+	t.items = t.items[:len(t.items)-1]
+	return last
+}
+func (t *topK) push(c candidate) {
+	if len(t.items) < t.k {
+		heap.Push(t, c)
+	} else if c.score > t.items[0].score {
+		t.items[0] = c
+		heap.Fix(t, 0)
+	}
+}
+
+// sorted returns the candidates from the best score.
+func (t *topK) sorted() []candidate {
+	sorted := make([]candidate, len(t.items))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		sorted[i] = heap.Pop(t).(candidate)
+	}
+	return sorted
+}
@@ -0,0 +1,330 @@
+package vectorindex
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// randomItems returns n items with random vectors of dimension dim.
+func randomItems(random *rand.Rand, prefix string, n, dim int) []Item {
+	items := make([]Item, n)
+	for i := range items {
+		vector := make([]float64, dim)
+		for d := range vector {
+			vector[d] = random.NormFloat64()
+		}
+		items[i] = Item{
+			ID:       fmt.Sprint(prefix, i),
+			Vector:   vector,
+			Text:     fmt.Sprint("text ", prefix, i),
+			Metadata: map[string]interface{}{"group": i % 3},
+		}
+	}
+	return items
+}
+
+// resultIDs returns the IDs of results.
+func resultIDs(results []Result) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+// exactSearch searches x without its approximate index.
+func exactSearch(t *testing.T, x *Index, vector []float64, k int, filter Filter) []Result {
+	t.Helper()
+	x.mu.Lock()
+	index := x.ivf
+	x.ivf = nil
+	x.mu.Unlock()
+	defer func() {
+		x.mu.Lock()
+		x.ivf = index
+		x.mu.Unlock()
+	}()
+	results, err := x.Search(vector, k, filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return results
+}
+
+// checkIVF checks that each item of x is in exactly one cluster of the
+// approximate index.
+func checkIVF(t *testing.T, x *Index) {
+	t.Helper()
+	if len(x.ivf.assign) != len(x.ids) {
+		t.Fatalf("got %d assigned items, want %d", len(x.ivf.assign), len(x.ids))
+	}
+	seen := make([]bool, len(x.ids))
+	for c, list := range x.ivf.lists {
+		for _, i := range list {
+			if i < 0 || i >= len(x.ids) || seen[i] || x.ivf.assign[i] != c {
+				t.Fatalf("item %d is misplaced in cluster %d", i, c)
+			}
+			seen[i] = true
+		}
+	}
+	for id, i := range x.byID {
+		if x.ids[i] != id {
+			t.Fatalf("item %s is at index %d, holding %s", id, i, x.ids[i])
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	items := []Item{
+		{ID: "a", Vector: []float64{1, 0}},
+		{ID: "b", Vector: []float64{0, 2}},
+		{ID: "c", Vector: []float64{3, 4}},
+	}
+	query := []float64{1, 0.2}
+	tests := []struct {
+		metric Metric
+		ids    []string
+		scores []float64
+	}{
+		{metric: Cosine, ids: []string{"a", "c", "b"}, scores: []float64{1 / math.Sqrt(1.04), 3.8 / 5 / math.Sqrt(1.04), 0.2 / math.Sqrt(1.04)}},
+		{metric: Dot, ids: []string{"c", "a", "b"}, scores: []float64{3.8, 1, 0.4}},
+		{metric: Euclidean, ids: []string{"a", "b", "c"}, scores: []float64{0.2, math.Sqrt(1 + 1.8*1.8), math.Sqrt(4 + 3.8*3.8)}},
+	}
+	for _, test := range tests {
+		x := New(Params{Metric: test.metric})
+		if err := x.Add(items...); err != nil {
+			t.Fatal(err)
+		}
+		results, err := x.Search(query, 3, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(resultIDs(results)) != fmt.Sprint(test.ids) {
+			t.Errorf("metric %d: got %v, want %v", test.metric, resultIDs(results), test.ids)
+			continue
+		}
+		for i, result := range results {
+			if math.Abs(result.Score-test.scores[i]) > 1e-5 {
+				t.Errorf("metric %d: got score %v for %s, want %v", test.metric, result.Score, result.ID, test.scores[i])
+			}
+		}
+	}
+}
+
+func TestAddDelete(t *testing.T) {
+	x := New(Params{})
+	if err := x.Add(randomItems(rand.New(rand.NewSource(1)), "", 5, 4)...); err != nil {
+		t.Fatal(err)
+	}
+	if err := x.Add(Item{ID: "x", Vector: []float64{1, 2}}); err == nil {
+		t.Error("got no error for a vector of another dimension")
+	}
+
+	// Replace an item, then delete another one, moving the last item
+	if err := x.Add(Item{ID: "2", Vector: []float64{0, 0, 0, 1}, Text: "replaced"}); err != nil {
+		t.Fatal(err)
+	}
+	if !x.Delete("1") || x.Delete("1") {
+		t.Error("got wrong existence reported by Delete")
+	}
+	if x.Len() != 4 {
+		t.Errorf("got %d items, want 4", x.Len())
+	}
+	if _, ok := x.Get("1"); ok {
+		t.Error("the deleted item is still stored")
+	}
+	if item, ok := x.Get("4"); !ok || item.Text != "text 4" {
+		t.Errorf("got moved item %+v", item)
+	}
+	results, err := x.Search([]float64{0, 0, 0, 1}, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 || results[0].ID != "2" || results[0].Text != "replaced" {
+		t.Errorf("got results %v", resultIDs(results))
+	}
+	if results, _ = x.Search([]float64{0, 0, 0, 1}, 10, Equals("group", 1)); fmt.Sprint(resultIDs(results)) != "[4]" {
+		t.Errorf("got filtered results %v, want [4]", resultIDs(results))
+	}
+}
+
+func TestDeleteMatching(t *testing.T) {
+	x := New(Params{})
+	if err := x.Add(randomItems(rand.New(rand.NewSource(1)), "", 30, 4)...); err != nil {
+		t.Fatal(err)
+	}
+	x.BuildIVF(IVFParams{NList: 3})
+	if n := x.DeleteMatching(Equals("group", 1)); n != 10 {
+		t.Errorf("got %d deleted items, want 10", n)
+	}
+	checkIVF(t, x)
+	for i := 0; i < 30; i++ {
+		if _, ok := x.Get(fmt.Sprint(i)); ok != (i%3 != 1) {
+			t.Errorf("item %d: got stored %v", i, ok)
+		}
+	}
+}
+
+func TestIVF(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	x := New(Params{Metric: Euclidean})
+	if err := x.Add(randomItems(random, "", 300, 8)...); err != nil {
+		t.Fatal(err)
+	}
+	x.BuildIVF(IVFParams{NList: 10, NProbe: 10})
+	checkIVF(t, x)
+
+	// Delete, replace and add items once the clusters are built
+	for i := 0; i < 300; i += 2 {
+		x.Delete(fmt.Sprint(i))
+	}
+	for i, item := range randomItems(random, "", 50, 8) {
+		item.ID = fmt.Sprint(2*i + 1)
+		if err := x.Add(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	added := randomItems(random, "new", 100, 8)
+	if err := x.Add(added...); err != nil {
+		t.Fatal(err)
+	}
+	checkIVF(t, x)
+	if x.Len() != 250 {
+		t.Fatalf("got %d items, want 250", x.Len())
+	}
+
+	// Probing all the clusters is exact
+	for _, item := range added[:20] {
+		results, err := x.Search(item.Vector, 5, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if results[0].ID != item.ID {
+			t.Errorf("got closest item %s, want %s", results[0].ID, item.ID)
+		}
+		if exact := exactSearch(t, x, item.Vector, 5, nil); fmt.Sprint(resultIDs(results)) != fmt.Sprint(resultIDs(exact)) {
+			t.Errorf("got %v, want %v", resultIDs(results), resultIDs(exact))
+		}
+	}
+
+	// Probing one cluster finds the items of the query cluster
+	x.mu.Lock()
+	x.ivf.nprobe = 1
+	x.mu.Unlock()
+	found := 0
+	for _, item := range added {
+		if results, _ := x.Search(item.Vector, 1, nil); len(results) == 1 && results[0].ID == item.ID {
+			found++
+		}
+	}
+	if found != len(added) {
+		t.Errorf("found %d items out of %d probing one cluster", found, len(added))
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	for _, withIVF := range []bool{false, true} {
+		t.Run(fmt.Sprint("ivf ", withIVF), func(t *testing.T) {
+			random := rand.New(rand.NewSource(1))
+			x := New(Params{Metric: Dot})
+			items := randomItems(random, "", 100, 6)
+			items[0].Metadata = map[string]interface{}{"lang": "en", "tags": []interface{}{"a", "b"}}
+			items[1].Metadata = nil
+			if err := x.Add(items...); err != nil {
+				t.Fatal(err)
+			}
+			if withIVF {
+				x.BuildIVF(IVFParams{NList: 5, NProbe: 2})
+			}
+			x.Delete("50")
+
+			path := filepath.Join(t.TempDir(), "index")
+			if err := x.SaveFile(path); err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := LoadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if loaded.Len() != 99 || loaded.metric != Dot || (loaded.ivf != nil) != withIVF {
+				t.Fatalf("got %d items, metric %d, ivf %v", loaded.Len(), loaded.metric, loaded.ivf != nil)
+			}
+			if withIVF {
+				checkIVF(t, loaded)
+				if loaded.ivf.nprobe != 2 {
+					t.Errorf("got nprobe %d, want 2", loaded.ivf.nprobe)
+				}
+			}
+			for _, id := range []string{"0", "1", "99"} {
+				want, _ := x.Get(id)
+				got, ok := loaded.Get(id)
+				if !ok || fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("got item %+v, want %+v", got, want)
+				}
+			}
+			if item, _ := loaded.Get("0"); item.Metadata["lang"] != "en" {
+				t.Errorf("got metadata %v", item.Metadata)
+			}
+			for _, item := range items[:10] {
+				want, _ := x.Search(item.Vector, 5, Equals("group", 1))
+				got, err := loaded.Search(item.Vector, 5, Equals("group", 1))
+				if err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+					t.Errorf("got results %v, want %v", resultIDs(got), resultIDs(want))
+				}
+			}
+
+			if _, err = Load(bytes.NewReader([]byte("not an index"))); err == nil {
+				t.Error("got no error loading garbage")
+			}
+		})
+	}
+}
+
+func TestConcurrentSaveDelete(t *testing.T) {
+	x := New(Params{})
+	items := randomItems(rand.New(rand.NewSource(1)), "", 200, 4)
+	if err := x.Add(items...); err != nil {
+		t.Fatal(err)
+	}
+	x.BuildIVF(IVFParams{})
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for _, item := range items {
+			x.Delete(item.ID)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			var buf bytes.Buffer
+			if err := x.Save(&buf); err != nil {
+				t.Error(err)
+				return
+			}
+			loaded, err := Load(&buf)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			// A snapshot holds each of its items once
+			ids := append([]string(nil), loaded.ids...)
+			sort.Strings(ids)
+			for j := 1; j < len(ids); j++ {
+				if ids[j] == ids[j-1] {
+					t.Errorf("snapshot holds %s twice", ids[j])
+					return
+				}
+			}
+		}
+	}()
+	wg.Wait()
+}
@@ -0,0 +1,58 @@
+package vectorindex
+
+import (
+	"errors"
+
+	"github.com/nlpcloud/nlpcloud-go"
+)
+
+// Document is a text to ingest in an Index.
+type Document struct {
+	ID       string
+	Text     string
+	Metadata map[string]interface{}
+}
+
+// Ingest extracts the embeddings of the documents with client, in chunks
+// sent concurrently, and adds them to the index. The documents whose
+// embeddings failed are not added, and are reported in the returned
+// *nlpcloud.PartialError.
+func (x *Index) Ingest(client *nlpcloud.Client, documents []Document, chunk nlpcloud.ChunkParams, opts ...nlpcloud.Option) error {
+	sentences := make([]string, len(documents))
+	for i, document := range documents {
+		sentences[i] = document.Text
+	}
+	embeddings, err := client.ChunkedEmbeddings(nlpcloud.EmbeddingsParams{Sentences: sentences}, chunk, opts...)
+	if err != nil {
+		return err
+	}
+
+	failed := map[int]bool{}
+	for _, failure := range embeddings.Failures {
+		failed[failure.Index] = true
+	}
+	items := make([]Item, 0, len(documents)-len(failed))
+	for i, document := range documents {
+		if failed[i] {
+			continue
+		}
+		items = append(items, Item{ID: document.ID, Vector: embeddings.Embeddings[i], Text: document.Text, Metadata: document.Metadata})
+	}
+	if err = x.Add(items...); err != nil {
+		return err
+	}
+	return embeddings.Err()
+}
+
+// SearchText extracts the embeddings of text with client, and returns the
+// k closest items selected by filter, which may be nil.
+func (x *Index) SearchText(client *nlpcloud.Client, text string, k int, filter Filter, opts ...nlpcloud.Option) ([]Result, error) {
+	embeddings, err := client.Embeddings(nlpcloud.EmbeddingsParams{Sentences: []string{text}}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings.Embeddings) != 1 {
+		return nil, errors.New("vectorindex: unexpected number of embeddings")
+	}
+	return x.Search(embeddings.Embeddings[0], k, filter)
+}
@@ -0,0 +1,217 @@
+package vectorindex
+
+import (
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// IVFParams wraps the parameters of the approximate index.
+type IVFParams struct {
+	// NList is the number of clusters. Default is the square root of the
+	// number of items.
+	NList int
+	// NProbe is the number of clusters searched. Higher values improve the
+	// recall of the searches, but slow them down. Default is NList / 16,
+	// and at least 1.
+	NProbe int
+	// Iterations is the number of k-means iterations. Default is 10.
+	Iterations int
+	// SampleSize is the number of items the clusters are trained on.
+	// Default is 64 * NList.
+	SampleSize int
+}
+
+// ivf is an inverted file index: the vectors are grouped in clusters, and
+// a search only scans the clusters closest to the query.
+type ivf struct {
+	centroids [][]float32
+	// lists holds the indices of the items of each cluster.
+	lists [][]int
+	// assign holds the cluster of each item.
+	assign map[int]int
+	nprobe int
+}
+
+// BuildIVF builds the approximate index, used by the next searches. The
+// items added afterwards are assigned to the closest cluster, so the index
+// should be built again once the collection changed significantly.
+//
+// The clusters are computed with k-means, using the euclidean distance:
+// searches by Dot product get a lower recall than by Cosine or Euclidean.
+func (x *Index) BuildIVF(params IVFParams) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	n := len(x.vectors)
+	if params.NList < 1 {
+		params.NList = int(math.Sqrt(float64(n)))
+	}
+	if params.NList > n {
+		params.NList = n
+	}
+	if params.NList < 1 {
+		x.ivf = nil
+		return
+	}
+	if params.NProbe < 1 {
+		params.NProbe = params.NList / 16
+		if params.NProbe < 1 {
+			params.NProbe = 1
+		}
+	}
+	if params.Iterations < 1 {
+		params.Iterations = 10
+	}
+	if params.SampleSize < 1 {
+		params.SampleSize = 64 * params.NList
+	}
+	if params.SampleSize > n {
+		params.SampleSize = n
+	}
+
+	// Train the centroids on a sample of the vectors
+	random := rand.New(rand.NewSource(1))
+	sample := make([][]float32, params.SampleSize)
+	for i, j := range random.Perm(n)[:params.SampleSize] {
+		sample[i] = x.vectors[j]
+	}
+	centroids := make([][]float32, params.NList)
+	for i := range centroids {
+		centroids[i] = append([]float32(nil), sample[i]...)
+	}
+	for iteration := 0; iteration < params.Iterations; iteration++ {
+		assign := nearestCentroids(centroids, sample)
+		sums := make([][]float64, len(centroids))
+		counts := make([]int, len(centroids))
+		for i, c := range assign {
+			if sums[c] == nil {
+				sums[c] = make([]float64, x.dim)
+			}
+			for d, v := range sample[i] {
+				sums[c][d] += float64(v)
+			}
+			counts[c]++
+		}
+		for c := range centroids {
+			if counts[c] == 0 {
+				// Reseed the empty clusters
+				copy(centroids[c], sample[random.Intn(len(sample))])
+				continue
+			}
+			for d := range centroids[c] {
+				centroids[c][d] = float32(sums[c][d] / float64(counts[c]))
+			}
+		}
+	}
+
+	index := &ivf{
+		centroids: centroids,
+		lists:     make([][]int, len(centroids)),
+		assign:    map[int]int{},
+		nprobe:    params.NProbe,
+	}
+	for i, c := range nearestCentroids(centroids, x.vectors) {
+		index.lists[c] = append(index.lists[c], i)
+		index.assign[i] = c
+	}
+	x.ivf = index
+}
+
+// DropIVF drops the approximate index, so the next searches are exact.
+func (x *Index) DropIVF() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.ivf = nil
+}
+
+// nearestCentroids returns the closest centroid of each vector, computed
+// concurrently.
+func nearestCentroids(centroids, vectors [][]float32) []int {
+	assign := make([]int, len(vectors))
+	workers := runtime.GOMAXPROCS(0)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < len(vectors); i += workers {
+				assign[i] = nearestCentroid(centroids, vectors[i])
+			}
+		}(w)
+	}
+	wg.Wait()
+	return assign
+}
+
+func nearestCentroid(centroids [][]float32, vector []float32) int {
+	best, bestDistance := 0, math.Inf(1)
+	for c, centroid := range centroids {
+		if d := squaredDistance(centroid, vector); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// probe returns the lists of the clusters closest to query.
+func (f *ivf) probe(query []float32) [][]int {
+	clusters := make([]int, len(f.centroids))
+	distances := make([]float64, len(f.centroids))
+	for c, centroid := range f.centroids {
+		clusters[c] = c
+		distances[c] = squaredDistance(centroid, query)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return distances[clusters[i]] < distances[clusters[j]] })
+
+	nprobe := f.nprobe
+	if nprobe > len(clusters) {
+		nprobe = len(clusters)
+	}
+	lists := make([][]int, nprobe)
+	for i, c := range clusters[:nprobe] {
+		lists[i] = f.lists[c]
+	}
+	return lists
+}
+
+// add assigns the item i to its closest cluster.
+func (f *ivf) add(i int, vector []float32) {
+	c := nearestCentroid(f.centroids, vector)
+	f.lists[c] = append(f.lists[c], i)
+	f.assign[i] = c
+}
+
+// remove removes the item i from its cluster.
+func (f *ivf) remove(i int) {
+	c, ok := f.assign[i]
+	if !ok {
+		return
+	}
+	list := f.lists[c]
+	for j, item := range list {
+		if item == i {
+			list[j] = list[len(list)-1]
+			f.lists[c] = list[:len(list)-1]
+			break
+		}
+	}
+	delete(f.assign, i)
+}
+
+// move renames the item from to to.
+func (f *ivf) move(from, to int) {
+	c, ok := f.assign[from]
+	if !ok {
+		return
+	}
+	for j, item := range f.lists[c] {
+		if item == from {
+			f.lists[c][j] = to
+			break
+		}
+	}
+	delete(f.assign, from)
+	f.assign[to] = c
+}
@@ -0,0 +1,154 @@
+package vectorindex
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// formatVersion is the version of the persisted index format.
+const formatVersion = 1
+
+// snapshot is the persisted form of an Index. The metadata are encoded in
+// JSON, as gob needs their concrete types.
+type snapshot struct {
+	Version  int
+	Metric   Metric
+	Dim      int
+	IDs      []string
+	Texts    []string
+	Metadata [][]byte
+	// Vectors holds the vectors end to end.
+	Vectors []float32
+
+	Centroids [][]float32
+	Assign    []int
+	NProbe    int
+}
+
+// Save writes the index, including its approximate index, to w. The
+// snapshot is copied under the lock, so the index may change while it is
+// written.
+func (x *Index) Save(w io.Writer) error {
+	x.mu.RLock()
+	s := snapshot{
+		Version:  formatVersion,
+		Metric:   x.metric,
+		Dim:      x.dim,
+		IDs:      append([]string(nil), x.ids...),
+		Texts:    append([]string(nil), x.texts...),
+		Metadata: make([][]byte, len(x.ids)),
+		Vectors:  make([]float32, 0, len(x.ids)*x.dim),
+	}
+	for i := range x.ids {
+		if x.metadata[i] != nil {
+			j, err := json.Marshal(x.metadata[i])
+			if err != nil {
+				x.mu.RUnlock()
+				return err
+			}
+			s.Metadata[i] = j
+		}
+		s.Vectors = append(s.Vectors, x.vectors[i]...)
+	}
+	if x.ivf != nil {
+		s.Centroids = x.ivf.centroids
+		s.NProbe = x.ivf.nprobe
+		s.Assign = make([]int, len(x.ids))
+		for i := range s.Assign {
+			s.Assign[i] = x.ivf.assign[i]
+		}
+	}
+	x.mu.RUnlock()
+
+	buffered := bufio.NewWriter(w)
+	if err := gob.NewEncoder(buffered).Encode(&s); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// SaveFile writes the index to the file at path, replacing it atomically.
+func (x *Index) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	err = x.Save(tmp)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Load reads an index written by Save.
+func Load(r io.Reader) (*Index, error) {
+	var s snapshot
+	if err := gob.NewDecoder(bufio.NewReader(r)).Decode(&s); err != nil {
+		return nil, err
+	}
+	if s.Version != formatVersion {
+		return nil, errors.New("vectorindex: unsupported format version")
+	}
+	if len(s.Texts) != len(s.IDs) || len(s.Metadata) != len(s.IDs) || len(s.Vectors) != len(s.IDs)*s.Dim {
+		return nil, errors.New("vectorindex: corrupted index")
+	}
+
+	x := &Index{
+		metric:   s.Metric,
+		dim:      s.Dim,
+		ids:      s.IDs,
+		texts:    s.Texts,
+		metadata: make([]map[string]interface{}, len(s.IDs)),
+		vectors:  make([][]float32, len(s.IDs)),
+		byID:     make(map[string]int, len(s.IDs)),
+	}
+	for i, id := range s.IDs {
+		x.byID[id] = i
+		x.vectors[i] = s.Vectors[i*s.Dim : (i+1)*s.Dim : (i+1)*s.Dim]
+		if s.Metadata[i] != nil {
+			if err := json.Unmarshal(s.Metadata[i], &x.metadata[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if s.Centroids != nil {
+		if len(s.Assign) != len(s.IDs) {
+			return nil, errors.New("vectorindex: corrupted index")
+		}
+		x.ivf = &ivf{
+			centroids: s.Centroids,
+			lists:     make([][]int, len(s.Centroids)),
+			assign:    make(map[int]int, len(s.IDs)),
+			nprobe:    s.NProbe,
+		}
+		for i, c := range s.Assign {
+			if c < 0 || c >= len(s.Centroids) {
+				return nil, errors.New("vectorindex: corrupted index")
+			}
+			x.ivf.lists[c] = append(x.ivf.lists[c], i)
+			x.ivf.assign[i] = c
+		}
+	}
+	return x, nil
+}
+
+// LoadFile reads an index written by SaveFile.
+func LoadFile(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Load(file)
+}